@@ -6,8 +6,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mattermost/mattermost/server/public/model"
@@ -25,8 +31,62 @@ type SetTranslationLanguageRequest struct {
 
 type TranslatePostRequest struct {
 	Lang string `json:"lang"`
+	// Permanent stores the translation on the post for everyone instead of only for the requester.
+	Permanent bool `json:"permanent"`
 }
 
+type TranslateFileRequest struct {
+	Lang string `json:"lang"`
+}
+
+type BatchTranslationsRequest struct {
+	PostIDs  []string `json:"post_ids"`
+	Lang     string   `json:"lang"`
+	Generate bool     `json:"generate"`
+}
+
+type BatchTranslationsResponse struct {
+	Translations map[string]string `json:"translations"`
+	Missing      []string          `json:"missing"`
+	// Attachments are the translated message attachments, keyed by post ID.
+	Attachments map[string][]TranslatedAttachment `json:"attachments"`
+	// Props are the translated props of custom posts, keyed by post ID and then by path.
+	Props map[string]map[string]string `json:"props"`
+}
+
+// PostTranslationProvenance describes how a stored translation of a post was made and when.
+type PostTranslationProvenance struct {
+	*TranslationProvenance
+	UpdateAt int64 `json:"updateAt"`
+}
+
+// TranslationUsageResponse is the usage of a channel, team or language over a month.
+type TranslationUsageResponse struct {
+	Month string                      `json:"month"`
+	Total TranslationUsage            `json:"total"`
+	Cost  float64                     `json:"cost"`
+	Days  map[string]TranslationUsage `json:"days"`
+}
+
+type PreviewTranslationRequest struct {
+	Text      string   `json:"text"`
+	ChannelID string   `json:"channel_id"`
+	Languages []string `json:"languages"`
+}
+
+type PreviewTranslationResponse struct {
+	Translations map[string]string   `json:"translations"`
+	Warnings     map[string][]string `json:"warnings"`
+	Errors       map[string]string   `json:"errors"`
+}
+
+const (
+	maxPreviewLanguages     = 10
+	maxBatchPosts           = 200
+	maxBatchGeneratedPosts  = 20
+	batchGenerationParallel = 3
+)
+
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
 	router := gin.Default()
 	router.Use(p.ginlogger)
@@ -34,9 +94,20 @@ func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 
 	router.POST("/channel/:channelid/translations", p.handleSetChannelTranslations)
 	router.GET("/channel/:channelid/translations", p.handleGetChannelTranslationStatus)
+	router.GET("/channel/:channelid/info_translation", p.handleGetChannelInfoTranslation)
+	router.POST("/channel/:channelid/primary_language", p.handleSetChannelPrimaryLanguage)
 	router.GET("/translation/languages", p.handleGetTranslationLanguages)
 	router.POST("/translation/user_preference", p.handleSetUserTranslationLanguage)
+	router.POST("/translation/outbound", p.handleSetOutboundTranslation)
+	router.POST("/translations/batch", p.handleGetTranslationsBatch)
 	router.POST("/post/:postid/translate", p.handleTranslatePost)
+	router.GET("/post/:postid/translations", p.handleGetPostTranslations)
+	router.POST("/post/:postid/translations/retry", p.handleRetryPostTranslations)
+	router.POST("/post/:postid/file/:fileid/translate", p.handleTranslatePostFile)
+	router.GET("/file/:fileid/translations", p.handleGetFileTranslations)
+	router.GET("/usage/:scope/:id", p.handleGetTranslationUsage)
+	router.POST("/translation/share", p.handleShareTranslation)
+	router.POST("/translation/preview", p.handlePreviewTranslation)
 
 	router.ServeHTTP(w, r)
 }
@@ -61,6 +132,50 @@ func getUserTranslationPreferenceKey(userID string) string {
 	return fmt.Sprintf("user_translation_preference_%s", userID)
 }
 
+// saveUserTranslationLanguage stores the language the user reads translations in.
+func (p *Plugin) saveUserTranslationLanguage(userID, lang string) error {
+	if _, err := p.pluginAPI.KV.Set(getUserTranslationPreferenceKey(userID), []byte(lang)); err != nil {
+		return fmt.Errorf("failed to save user translation language: %w", err)
+	}
+	return nil
+}
+
+// getUserTranslationLanguage returns the language the user reads translations in, or an empty
+// string if the user has none. The language is stored as raw bytes rather than JSON.
+func (p *Plugin) getUserTranslationLanguage(userID string) (string, error) {
+	var preference []byte
+	if err := p.pluginAPI.KV.Get(getUserTranslationPreferenceKey(userID), &preference); err != nil {
+		return "", fmt.Errorf("failed to get user translation language: %w", err)
+	}
+	return string(preference), nil
+}
+
+// resolveUserTranslationLanguage returns the language the user reads translations in: the one
+// chosen through the plugin, then the one in the user settings of the webapp, then the locale of
+// the user, as the webapp does.
+func (p *Plugin) resolveUserTranslationLanguage(userID string) (string, error) {
+	lang, err := p.getUserTranslationLanguage(userID)
+	if err != nil {
+		return "", err
+	}
+	if lang != "" {
+		return lang, nil
+	}
+
+	if preference, appErr := p.API.GetPreferenceForUser(userID, userSettingsCategory, userSettingsLanguageOption); appErr == nil && preference.Value != "" {
+		return preference.Value, nil
+	}
+
+	user, err := p.pluginAPI.User.Get(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Locale == "" {
+		return "en", nil
+	}
+	return user.Locale, nil
+}
+
 func (p *Plugin) handleGetTranslationLanguages(c *gin.Context) {
 	userID := c.GetHeader("Mattermost-User-Id")
 	configuredLanguages := []string{}
@@ -70,8 +185,7 @@ func (p *Plugin) handleGetTranslationLanguages(c *gin.Context) {
 			configuredLanguages[i] = strings.TrimSpace(lang)
 		}
 	}
-	var preference string
-	_ = p.pluginAPI.KV.Get(getUserTranslationPreferenceKey(userID), &preference)
+	preference, _ := p.getUserTranslationLanguage(userID)
 	response := TranslationLanguagesResponse{
 		Languages:      configuredLanguages,
 		UserPreference: preference,
@@ -79,6 +193,25 @@ func (p *Plugin) handleGetTranslationLanguages(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (p *Plugin) handleSetOutboundTranslation(c *gin.Context) {
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := p.setOutboundTranslationEnabled(userID, req.Enabled); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": req.Enabled})
+}
+
 func (p *Plugin) handleSetUserTranslationLanguage(c *gin.Context) {
 	userID := c.GetHeader("Mattermost-User-Id")
 
@@ -88,7 +221,12 @@ func (p *Plugin) handleSetUserTranslationLanguage(c *gin.Context) {
 		return
 	}
 
-	if _, err := p.pluginAPI.KV.Set(getUserTranslationPreferenceKey(userID), []byte(req.Language)); err != nil {
+	if req.Language != "" && !p.isSelectableLanguage(req.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	if err := p.saveUserTranslationLanguage(userID, req.Language); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preference"})
 		return
 	}
@@ -117,66 +255,482 @@ func (p *Plugin) handleTranslatePost(c *gin.Context) {
 		return
 	}
 
-	if post.Message == "" {
+	if !p.isAllowedLanguage(post.ChannelId, req.Lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	if req.Permanent {
+		channel, err := p.pluginAPI.Channel.Get(post.ChannelId)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		if !p.canManageChannelTranslations(userID, channel) {
+			c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to store translations of the post"))
+			return
+		}
+	}
+
+	if !p.hasTranslatableContent(post) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot translate empty message"})
 		return
 	}
 
-	if isSystemMessage(post) && !p.getConfiguration().TranslateSystemMessages {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "System messages translation is disabled"})
+	if !p.isTranslatedPostType(post.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Posts of this type are not translated"})
 		return
 	}
 
-	translatedText, err := p.translateText(post.Message, userID, req.Lang)
+	if err := p.checkOnDemandLimits(userID, post.ChannelId, p.countTranslationRequests(post)); err != nil {
+		p.respondLimitError(c, err)
+		return
+	}
+
+	content, err := p.translatePostContent(post, userID, req.Lang)
 	if err != nil {
 		p.pluginAPI.Log.Error("Failed to translate post", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to translate post"})
 		return
 	}
 
-	if post.Props == nil {
-		post.Props = make(model.StringInterface)
+	if req.Permanent {
+		err = p.storePostTranslation(post, req.Lang, content, userID)
+	} else {
+		err = p.savePersonalTranslation(userID, post, req.Lang, content, p.newTranslationProvenance(userID))
 	}
-
-	translations, ok := post.Props["translations"].(map[string]interface{})
-	if !ok {
-		translations = make(map[string]interface{})
+	if errors.Is(err, errStaleTranslation) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post was modified while it was being translated"})
+		return
 	}
-
-	translations[req.Lang] = translatedText
-	post.Props["translations"] = translations
-
-	if err := p.pluginAPI.Post.UpdatePost(post); err != nil {
+	if err != nil {
 		p.pluginAPI.Log.Error("Failed to update post with translation", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post with translation"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"translatedText": translatedText,
+		"translatedText": content.Text,
 		"originalText":   post.Message,
+		"attachments":    content.Attachments,
+		"props":          content.Props,
 		"targetLanguage": req.Lang,
+		"permanent":      req.Permanent,
 	})
 }
 
-func (p *Plugin) handleSetChannelTranslations(c *gin.Context) {
-	channelID := c.Param("channelid")
+func (p *Plugin) handleGetTranslationsBatch(c *gin.Context) {
 	userID := c.GetHeader("Mattermost-User-Id")
 
-	channel, err := p.pluginAPI.Channel.Get(channelID)
+	var req BatchTranslationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Lang == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Language is required"})
+		return
+	}
+
+	if !isSupportedLanguage(req.Lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	if len(req.PostIDs) > maxBatchPosts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot request more than %d posts at once", maxBatchPosts)})
+		return
+	}
+
+	// Check read permission and the allowed languages per post, caching the result per channel
+	readableChannels := make(map[string]bool)
+	posts := []*model.Post{}
+	for _, postID := range req.PostIDs {
+		post, err := p.pluginAPI.Post.GetPost(postID)
+		if err != nil {
+			continue
+		}
+
+		canRead, checked := readableChannels[post.ChannelId]
+		if !checked {
+			canRead = p.pluginAPI.User.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) &&
+				p.isAllowedLanguage(post.ChannelId, req.Lang)
+			readableChannels[post.ChannelId] = canRead
+		}
+		if !canRead {
+			continue
+		}
+
+		if err := p.migratePost(post); err != nil && !errors.Is(err, errStaleTranslation) {
+			p.pluginAPI.Log.Warn("Failed to migrate post translations", "post_id", post.Id, "error", err.Error())
+		}
+
+		posts = append(posts, post)
+	}
+
+	stored, err := p.getStoredTranslations(posts, req.Lang)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	response := BatchTranslationsResponse{
+		Translations: make(map[string]string),
+		Missing:      []string{},
+		Attachments:  make(map[string][]TranslatedAttachment),
+		Props:        make(map[string]map[string]string),
+	}
+	for postID, translation := range stored {
+		response.Translations[postID] = translation.Text
+		if len(translation.Attachments) > 0 {
+			response.Attachments[postID] = translation.Attachments
+		}
+		if len(translation.Props) > 0 {
+			response.Props[postID] = translation.Props
+		}
+	}
+
+	// Translations the user asked for themselves fill in the ones missing on the posts
+	unstored := []*model.Post{}
+	for _, post := range posts {
+		if _, ok := stored[post.Id]; !ok {
+			unstored = append(unstored, post)
+		}
+	}
+	personal, err := p.getPersonalTranslations(userID, unstored, req.Lang)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	for postID, translation := range personal {
+		response.Translations[postID] = translation.Text
+		if len(translation.Attachments) > 0 {
+			response.Attachments[postID] = translation.Attachments
+		}
+		if len(translation.Props) > 0 {
+			response.Props[postID] = translation.Props
+		}
+	}
+
+	missing := []*model.Post{}
+	for _, post := range posts {
+		if _, ok := response.Translations[post.Id]; ok {
+			continue
+		}
+		if req.Generate && p.isTranslatable(post) && len(missing) < maxBatchGeneratedPosts {
+			missing = append(missing, post)
+			continue
+		}
+		response.Missing = append(response.Missing, post.Id)
+	}
+
+	// Translate the posts that have no translation yet on demand, only for the requester
+	provenance := p.newTranslationProvenance(userID)
+	waitGroup := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+	waitlist := make(chan struct{}, batchGenerationParallel)
+	for i, post := range missing {
+		if err := p.checkOnDemandLimits(userID, post.ChannelId, p.countTranslationRequests(post)); err != nil {
+			// Posts that can never fit in the limits are skipped, and the others are left for a
+			// later request
+			var tooLarge *errRequestTooLarge
+			if errors.As(err, &tooLarge) {
+				mutex.Lock()
+				response.Missing = append(response.Missing, post.Id)
+				mutex.Unlock()
+				continue
+			}
+			var limited *errRateLimited
+			if !errors.As(err, &limited) {
+				p.pluginAPI.Log.Warn("Failed to check translation limits", "error", err.Error())
+			}
+			mutex.Lock()
+			for _, skipped := range missing[i:] {
+				response.Missing = append(response.Missing, skipped.Id)
+			}
+			mutex.Unlock()
+			break
+		}
+
+		waitlist <- struct{}{}
+		waitGroup.Add(1)
+		go func(post *model.Post) {
+			defer waitGroup.Done()
+			defer func() { <-waitlist }()
+
+			content, err := p.translatePostContent(post, userID, req.Lang)
+			if err == nil {
+				err = p.savePersonalTranslation(userID, post, req.Lang, content, provenance)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				p.pluginAPI.Log.Warn("Failed to translate post on demand", "post_id", post.Id, "error", err.Error())
+				response.Missing = append(response.Missing, post.Id)
+				return
+			}
+			response.Translations[post.Id] = content.Text
+			if len(content.Attachments) > 0 {
+				response.Attachments[post.Id] = content.Attachments
+			}
+			if len(content.Props) > 0 {
+				response.Props[post.Id] = content.Props
+			}
+		}(post)
+	}
+	waitGroup.Wait()
+	close(waitlist)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (p *Plugin) handleGetPostTranslations(c *gin.Context) {
+	postID := c.Param("postid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	post, err := p.pluginAPI.Post.GetPost(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read post"))
+		return
+	}
+
+	if err := p.migratePost(post); err != nil && !errors.Is(err, errStaleTranslation) {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	stored, err := p.getStoredPostTranslations(post)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	hasPermission := false
+	translations := make(map[string]string)
+	provenance := make(map[string]PostTranslationProvenance)
+	attachments := make(map[string][]TranslatedAttachment)
+	props := make(map[string]map[string]string)
+	for lang, translation := range stored {
+		translations[lang] = translation.Text
+		if len(translation.Attachments) > 0 {
+			attachments[lang] = translation.Attachments
+		}
+		if len(translation.Props) > 0 {
+			props[lang] = translation.Props
+		}
+		provenance[lang] = PostTranslationProvenance{
+			TranslationProvenance: translation.Provenance,
+			UpdateAt:              translation.UpdateAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"translations": translations,
+		"provenance":   provenance,
+		"attachments":  attachments,
+		"props":        props,
+	})
+}
+
+func (p *Plugin) handleRetryPostTranslations(c *gin.Context) {
+	postID := c.Param("postid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	post, err := p.pluginAPI.Post.GetPost(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read post"))
+		return
+	}
+
+	failed := getFailedLanguages(post)
+	if len(failed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"languages": failed})
+		return
+	}
+
+	// Retries translate the post for everyone, so they follow the same rules as automatic
+	// translations
+	if !p.shouldAutoTranslate(post) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Translations are disabled or paused in this channel"})
+		return
+	}
+
+	// Every language is translated again, with each of the texts of the post counting against the limits
+	if err := p.checkOnDemandLimits(userID, post.ChannelId, len(failed)*p.countTranslationRequests(post)); err != nil {
+		p.respondLimitError(c, err)
+		return
+	}
+
+	err = p.updatePostTranslations(post, func(current *model.Post) error {
+		statuses := getPostTranslationStatuses(current)
+		for _, lang := range failed {
+			statuses[lang] = LanguageStatus{Status: TranslationStatusPending}
+		}
+		current.AddProp(translationStatusProp, statuses)
+		return nil
+	})
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to update post translation status", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post translation status"})
+		return
+	}
+
+	go p.translatePost(post, nil, userID, userID, failed)
+
+	c.JSON(http.StatusOK, gin.H{"languages": failed})
+}
+
+// handleTranslatePostFile translates a transcript or text file attached to the post in the
+// background and replies in the thread with the translated file.
+func (p *Plugin) handleTranslatePostFile(c *gin.Context) {
+	postID := c.Param("postid")
+	fileID := c.Param("fileid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	var req TranslateFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, err := p.pluginAPI.Post.GetPost(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read post"))
+		return
+	}
+
+	if !slices.Contains(post.FileIds, fileID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File is not attached to the post"})
+		return
+	}
+
+	if !p.isAllowedLanguage(post.ChannelId, req.Lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	info, err := p.pluginAPI.File.GetInfo(fileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	if !isTranslatableFile(info) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only VTT, SRT, text and Markdown files can be translated"})
+		return
+	}
+
+	maxSize := p.getConfiguration().getMaxFileTranslationSize()
+	if info.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Files larger than %d KB can't be translated", maxSize/1024)})
+		return
+	}
+
+	reader, err := p.pluginAPI.File.Get(fileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+	content, err := io.ReadAll(io.LimitReader(reader, maxSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	// Every batch of cues or chunk of text is a request of its own
+	cost := countFileRequests(info, string(content))
+	if cost == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File has no text to translate"})
+		return
+	}
+	if err := p.checkOnDemandLimits(userID, post.ChannelId, cost); err != nil {
+		p.respondLimitError(c, err)
+		return
+	}
+
+	go p.translatePostFile(post, info, string(content), userID, req.Lang)
+
+	c.JSON(http.StatusAccepted, gin.H{"status": TranslationStatusPending})
+}
+
+// handleGetFileTranslations returns the translated files of a file attachment, keyed by language.
+func (p *Plugin) handleGetFileTranslations(c *gin.Context) {
+	fileID := c.Param("fileid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	info, err := p.pluginAPI.File.GetInfo(fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, info.ChannelId, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read file"))
+		return
+	}
+
+	files, err := p.getTranslatedFiles(fileID)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"translations": files})
+}
+
+// respondLimitError answers with the error of checking the limits of on-demand translations,
+// telling clients when to try again if a limit was reached.
+func (p *Plugin) respondLimitError(c *gin.Context, err error) {
+	var limited *errRateLimited
+	if errors.As(err, &limited) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limited.retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": limited.Error()})
+		return
+	}
+	var tooLarge *errRequestTooLarge
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tooLarge.Error()})
+		return
+	}
+	c.AbortWithError(http.StatusInternalServerError, err)
+}
+
+// canManageChannelTranslations checks if the user may change the translations of the channel and
+// of its posts for everyone. System admins have every channel permission.
+func (p *Plugin) canManageChannelTranslations(userID string, channel *model.Channel) bool {
 	if channel.Type == model.ChannelTypePrivate {
-		hasPermission = p.pluginAPI.User.HasPermissionToChannel(userID, channelID, model.PermissionManagePrivateChannelProperties)
-	} else {
-		hasPermission = p.pluginAPI.User.HasPermissionToChannel(userID, channelID, model.PermissionManagePublicChannelProperties)
+		return p.pluginAPI.User.HasPermissionToChannel(userID, channel.Id, model.PermissionManagePrivateChannelProperties)
+	}
+	return p.pluginAPI.User.HasPermissionToChannel(userID, channel.Id, model.PermissionManagePublicChannelProperties)
+}
+
+func (p *Plugin) handleSetChannelTranslations(c *gin.Context) {
+	channelID := c.Param("channelid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	channel, err := p.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
 
-	if !hasPermission {
+	if !p.canManageChannelTranslations(userID, channel) {
 		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to manage channel"))
 		return
 	}
@@ -189,7 +743,7 @@ func (p *Plugin) handleSetChannelTranslations(c *gin.Context) {
 		return
 	}
 
-	if err := p.setChannelTranslationEnabled(channelID, data.Enabled); err != nil {
+	if err := p.toggleChannelTranslations(channelID, userID, data.Enabled); err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
@@ -216,7 +770,290 @@ func (p *Plugin) handleGetChannelTranslationStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]bool{
-		"enabled": enabled,
+	primaryLanguage, err := p.getChannelPrimaryLanguage(channelID)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":         enabled,
+		"primaryLanguage": primaryLanguage,
+	})
+}
+
+// handleGetChannelInfoTranslation returns the header and purpose of the channel in the requested
+// language, or in the user's language when none is requested. The originals are returned while
+// there is no translation of the current header and purpose.
+func (p *Plugin) handleGetChannelInfoTranslation(c *gin.Context) {
+	channelID := c.Param("channelid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	// Check if user has read permissions for the channel
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, channelID, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read channel"))
+		return
+	}
+
+	lang := c.Query("lang")
+	if lang != "" && !p.isAllowedLanguage(channelID, lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+	if lang == "" {
+		preference, err := p.resolveUserTranslationLanguage(userID)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		lang = preference
+	}
+
+	channel, err := p.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// The language of the user may not be one the channel is translated to, which leaves the
+	// header and purpose as they are
+	var translation *ChannelInfoTranslation
+	if p.isAllowedLanguage(channelID, lang) {
+		translation, err = p.getChannelInfoTranslation(channel, lang)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if translation == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"language":   lang,
+			"header":     channel.Header,
+			"purpose":    channel.Purpose,
+			"translated": false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"language":   lang,
+		"header":     translation.Header,
+		"purpose":    translation.Purpose,
+		"translated": true,
 	})
 }
+
+func (p *Plugin) handleSetChannelPrimaryLanguage(c *gin.Context) {
+	channelID := c.Param("channelid")
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	channel, err := p.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if !p.canManageChannelTranslations(userID, channel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to manage channel"))
+		return
+	}
+
+	var req SetTranslationLanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Language != "" && !isSupportedLanguage(req.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	if err := p.setChannelPrimaryLanguage(channelID, req.Language); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"primaryLanguage": req.Language})
+}
+
+func (p *Plugin) handleGetTranslationUsage(c *gin.Context) {
+	userID := c.GetHeader("Mattermost-User-Id")
+	scope := c.Param("scope")
+	id := c.Param("id")
+
+	if !p.pluginAPI.User.HasPermissionTo(userID, model.PermissionManageSystem) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to see translation usage"))
+		return
+	}
+
+	if scope != usageScopeChannel && scope != usageScopeTeam && scope != usageScopeLanguage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown usage scope"})
+		return
+	}
+
+	month := time.Now().UTC()
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if requested := c.Query("month"); requested != "" {
+		parsed, err := time.Parse(monthFormat, requested)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Month must be formatted as YYYY-MM"})
+			return
+		}
+		month = parsed
+	}
+
+	days, err := p.getMonthlyUsage(scope, id, month)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	response := TranslationUsageResponse{
+		Month: month.Format(monthFormat),
+		Days:  days,
+	}
+	for _, usage := range days {
+		response.Total.Requests += usage.Requests
+		response.Total.InputTokens += usage.InputTokens
+		response.Total.OutputTokens += usage.OutputTokens
+	}
+	response.Cost = float64(response.Total.totalTokens()) / 1e6 * p.getConfiguration().CostPerMillionTokens
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleShareTranslation posts a translation made with `/translate text` to the channel, on
+// behalf of the user who asked for it. The original text is kept in the post props.
+func (p *Plugin) handleShareTranslation(c *gin.Context) {
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	var req model.PostActionIntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, _ := req.Context["translation"].(string)
+	original, _ := req.Context["original"].(string)
+	rootID, _ := req.Context["root_id"].(string)
+	signature, _ := req.Context["signature"].(string)
+	if translation == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Translation is required"})
+		return
+	}
+
+	// Only translations the plugin made for the user in this channel and thread are shared
+	if !p.hasValidShareSignature(signature, userID, req.ChannelId, rootID, original, translation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation"})
+		return
+	}
+
+	// Translations made inside a thread are shared as a reply in it
+	if rootID != "" {
+		root, err := p.pluginAPI.Post.GetPost(rootID)
+		if err != nil || root.ChannelId != req.ChannelId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread"})
+			return
+		}
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, req.ChannelId, model.PermissionCreatePost) {
+		c.JSON(http.StatusOK, model.PostActionIntegrationResponse{EphemeralText: "You don't have permission to post in this channel."})
+		return
+	}
+
+	post := &model.Post{
+		UserId:    userID,
+		ChannelId: req.ChannelId,
+		RootId:    rootID,
+		Message:   translation,
+	}
+	p.signOriginalMessage(post, original)
+	if err := p.pluginAPI.Post.CreatePost(post); err != nil {
+		p.pluginAPI.Log.Error("Failed to share translation", "error", err.Error())
+		c.JSON(http.StatusOK, model.PostActionIntegrationResponse{EphemeralText: "Failed to share the translation."})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.PostActionIntegrationResponse{})
+}
+
+// handlePreviewTranslation translates a draft into the given languages, so its author can check
+// how it will read before posting it. Nothing is stored.
+func (p *Plugin) handlePreviewTranslation(c *gin.Context) {
+	userID := c.GetHeader("Mattermost-User-Id")
+
+	var req PreviewTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot translate empty message"})
+		return
+	}
+
+	if len(req.Languages) == 0 || len(req.Languages) > maxPreviewLanguages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Between 1 and %d languages are required", maxPreviewLanguages)})
+		return
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(userID, req.ChannelID, model.PermissionReadChannel) {
+		c.AbortWithError(http.StatusForbidden, errors.New("user doesn't have permission to read channel"))
+		return
+	}
+
+	languages := []string{}
+	for _, lang := range req.Languages {
+		if !p.isAllowedLanguage(req.ChannelID, lang) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported language %s", lang)})
+			return
+		}
+		if !slices.Contains(languages, lang) {
+			languages = append(languages, lang)
+		}
+	}
+
+	if err := p.checkOnDemandLimits(userID, req.ChannelID, len(languages)); err != nil {
+		p.respondLimitError(c, err)
+		return
+	}
+
+	response := PreviewTranslationResponse{
+		Translations: make(map[string]string),
+		Warnings:     make(map[string][]string),
+		Errors:       make(map[string]string),
+	}
+	waitGroup := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+	waitlist := make(chan struct{}, batchGenerationParallel)
+	for _, lang := range languages {
+		waitlist <- struct{}{}
+		waitGroup.Add(1)
+		go func(langCode string) {
+			defer waitGroup.Done()
+			defer func() { <-waitlist }()
+
+			translation, err := p.translateText(req.Text, userID, req.ChannelID, langCode)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				p.pluginAPI.Log.Warn("Failed to preview translation", "language", langCode, "error", err.Error())
+				response.Errors[langCode] = "Failed to translate"
+				return
+			}
+			response.Translations[langCode] = translation
+			if warnings := validatePlaceholders(req.Text, translation); len(warnings) > 0 {
+				response.Warnings[langCode] = warnings
+			}
+		}(lang)
+	}
+	waitGroup.Wait()
+	close(waitlist)
+
+	c.JSON(http.StatusOK, response)
+}
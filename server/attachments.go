@@ -0,0 +1,154 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// TranslatedAttachment is the translation of the text of a message attachment. Translated
+// attachments are stored in the same order as the attachments of the post, and everything that is
+// not text, such as actions, colors and links, is left out and kept from the original.
+type TranslatedAttachment struct {
+	Pretext string            `json:"pretext,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	Text    string            `json:"text,omitempty"`
+	Fields  []TranslatedField `json:"fields,omitempty"`
+}
+
+// TranslatedField is the translation of a field of a message attachment.
+type TranslatedField struct {
+	Title string `json:"title,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// getAttachmentTexts returns the texts of the message attachments of the post, in order.
+func getAttachmentTexts(post *model.Post) []string {
+	texts := []string{}
+	for _, attachment := range post.Attachments() {
+		texts = append(texts, attachment.Pretext, attachment.Title, attachment.Text)
+		for _, field := range attachment.Fields {
+			value, _ := field.Value.(string)
+			texts = append(texts, field.Title, value)
+		}
+	}
+	return texts
+}
+
+// hasAttachmentText checks if the message attachments of the post have any text to translate.
+func hasAttachmentText(post *model.Post) bool {
+	for _, text := range getAttachmentTexts(post) {
+		if strings.TrimSpace(text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTranslatableContent checks if the post has any text the plugin translates.
+func (p *Plugin) hasTranslatableContent(post *model.Post) bool {
+	return post.Message != "" || hasAttachmentText(post) || len(p.getPropTexts(post)) > 0
+}
+
+// countTranslationRequests returns how many translation requests the content of the post takes:
+// one for the message, one for every distinct text of its attachments and one for every text of
+// its props.
+func (p *Plugin) countTranslationRequests(post *model.Post) int {
+	count := len(p.getPropTexts(post))
+	if post.Message != "" {
+		count++
+	}
+
+	texts := make(map[string]bool)
+	for _, text := range getAttachmentTexts(post) {
+		if strings.TrimSpace(text) != "" {
+			texts[text] = true
+		}
+	}
+	return count + len(texts)
+}
+
+// hasSameContent checks if both posts have the same text to translate.
+func (p *Plugin) hasSameContent(post, other *model.Post) bool {
+	return p.hashPostContent(post) == p.hashPostContent(other)
+}
+
+// translateAttachments translates the text of the message attachments of the post into a
+// language. Texts repeated across attachments are translated once.
+func (p *Plugin) translateAttachments(post *model.Post, requestorID, langCode string) ([]TranslatedAttachment, error) {
+	if !hasAttachmentText(post) {
+		return nil, nil
+	}
+
+	translated := make(map[string]string)
+	translate := func(text string) (string, error) {
+		if strings.TrimSpace(text) == "" {
+			return text, nil
+		}
+		if translation, ok := translated[text]; ok {
+			return translation, nil
+		}
+		translation, err := p.translateWithRetry(text, requestorID, post.ChannelId, langCode)
+		if err != nil {
+			return "", err
+		}
+		translated[text] = translation
+		return translation, nil
+	}
+
+	attachments := []TranslatedAttachment{}
+	for _, attachment := range post.Attachments() {
+		var translation TranslatedAttachment
+		var err error
+		if translation.Pretext, err = translate(attachment.Pretext); err != nil {
+			return nil, err
+		}
+		if translation.Title, err = translate(attachment.Title); err != nil {
+			return nil, err
+		}
+		if translation.Text, err = translate(attachment.Text); err != nil {
+			return nil, err
+		}
+
+		for _, field := range attachment.Fields {
+			var translatedField TranslatedField
+			if translatedField.Title, err = translate(field.Title); err != nil {
+				return nil, err
+			}
+			value, _ := field.Value.(string)
+			if translatedField.Value, err = translate(value); err != nil {
+				return nil, err
+			}
+			translation.Fields = append(translation.Fields, translatedField)
+		}
+
+		attachments = append(attachments, translation)
+	}
+	return attachments, nil
+}
+
+// isSameAttachmentText checks if the translated attachments read the same as the originals, which
+// happens when they are already written in the language.
+func isSameAttachmentText(post *model.Post, attachments []TranslatedAttachment) bool {
+	translatedTexts := []string{}
+	for _, attachment := range attachments {
+		translatedTexts = append(translatedTexts, attachment.Pretext, attachment.Title, attachment.Text)
+		for _, field := range attachment.Fields {
+			translatedTexts = append(translatedTexts, field.Title, field.Value)
+		}
+	}
+
+	texts := getAttachmentTexts(post)
+	if len(texts) != len(translatedTexts) {
+		return false
+	}
+	for i := range texts {
+		if strings.TrimSpace(texts[i]) != strings.TrimSpace(translatedTexts[i]) {
+			return false
+		}
+	}
+	return true
+}
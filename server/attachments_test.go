@@ -0,0 +1,51 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+func TestCountTranslationRequests(t *testing.T) {
+	p := &Plugin{}
+
+	for name, tc := range map[string]struct {
+		message     string
+		attachments []*model.SlackAttachment
+		expected    int
+	}{
+		"message only": {
+			message:  "Hello",
+			expected: 1,
+		},
+		"attachments only": {
+			attachments: []*model.SlackAttachment{{Title: "Alert", Text: "Disk full"}},
+			expected:    2,
+		},
+		"repeated and blank texts": {
+			message: "Hello",
+			attachments: []*model.SlackAttachment{
+				{Title: "Alert", Text: " "},
+				{Title: "Alert", Fields: []*model.SlackAttachmentField{{Title: "Host", Value: "db1"}, {Title: "Count", Value: 3}}},
+			},
+			expected: 5,
+		},
+		"nothing to translate": {
+			expected: 0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post := &model.Post{Message: tc.message}
+			if tc.attachments != nil {
+				model.ParseSlackAttachment(post, tc.attachments)
+			}
+
+			if count := p.countTranslationRequests(post); count != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const channelInfoTranslationKeyPrefix = "channel_info_translation"
+
+// ChannelInfoTranslation is the translation of the header and purpose of a channel into a language.
+type ChannelInfoTranslation struct {
+	Header  string `json:"header"`
+	Purpose string `json:"purpose"`
+	// SourceHash identifies the header and purpose that were translated.
+	SourceHash string                 `json:"sourceHash"`
+	UpdateAt   int64                  `json:"updateAt"`
+	Provenance *TranslationProvenance `json:"provenance,omitempty"`
+}
+
+func getChannelInfoTranslationKey(channelID, langCode string) string {
+	return fmt.Sprintf("%s_%s_%s", channelInfoTranslationKeyPrefix, channelID, langCode)
+}
+
+// hashChannelInfo returns the hash used to tell the versions of the header and purpose of a
+// channel apart.
+func hashChannelInfo(channel *model.Channel) string {
+	return hashMessage(channel.Header + "\x00" + channel.Purpose)
+}
+
+// isChannelInfoChange checks if the post is the system message of a change of the header or the
+// purpose of its channel.
+func isChannelInfoChange(post *model.Post) bool {
+	return post.Type == model.PostTypeHeaderChange || post.Type == model.PostTypePurposeChange
+}
+
+// getChannelInfoTranslation returns the translation of the current header and purpose of the
+// channel into a language, or nil if there is none.
+func (p *Plugin) getChannelInfoTranslation(channel *model.Channel, langCode string) (*ChannelInfoTranslation, error) {
+	var translation *ChannelInfoTranslation
+	if err := p.pluginAPI.KV.Get(getChannelInfoTranslationKey(channel.Id, langCode), &translation); err != nil {
+		return nil, fmt.Errorf("failed to get channel info translation: %w", err)
+	}
+	if translation == nil || translation.SourceHash != hashChannelInfo(channel) {
+		return nil, nil
+	}
+	return translation, nil
+}
+
+// translateChannelInfo translates the header and purpose of the channel into the languages of the
+// channel and stores them. Languages that already have a translation of the current header and
+// purpose are skipped.
+func (p *Plugin) translateChannelInfo(channelID, requestorID, requestedBy string) {
+	channel, err := p.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to get channel", "channel_id", channelID, "error", err.Error())
+		return
+	}
+
+	provenance := p.newTranslationProvenance(requestedBy)
+	for _, lang := range p.getChannelTranslationLanguages(channel.Id) {
+		stored, err := p.getChannelInfoTranslation(channel, lang)
+		if err != nil {
+			p.pluginAPI.Log.Warn("Failed to get channel info translation", "channel_id", channel.Id, "language", lang, "error", err.Error())
+			continue
+		}
+		if stored != nil {
+			continue
+		}
+
+		translation := ChannelInfoTranslation{
+			SourceHash: hashChannelInfo(channel),
+			UpdateAt:   model.GetMillis(),
+			Provenance: provenance,
+		}
+		if channel.Header != "" {
+			if translation.Header, err = p.translateWithRetry(channel.Header, requestorID, channel.Id, lang); err != nil {
+				p.pluginAPI.Log.Warn("Failed to translate channel header", "channel_id", channel.Id, "language", lang, "error", err.Error())
+				continue
+			}
+		}
+		if channel.Purpose != "" {
+			if translation.Purpose, err = p.translateWithRetry(channel.Purpose, requestorID, channel.Id, lang); err != nil {
+				p.pluginAPI.Log.Warn("Failed to translate channel purpose", "channel_id", channel.Id, "language", lang, "error", err.Error())
+				continue
+			}
+		}
+
+		if _, err := p.pluginAPI.KV.Set(getChannelInfoTranslationKey(channel.Id, lang), translation); err != nil {
+			p.pluginAPI.Log.Warn("Failed to save channel info translation", "channel_id", channel.Id, "language", lang, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+const (
+	commandTrigger = "translate"
+
+	pluginID = "mattermost-channel-translations"
+
+	// userSettingsCategory is the preference category of the plugin user settings in the webapp:
+	// "pp_" followed by the plugin ID, cut to the 32 characters preference categories can hold.
+	userSettingsCategory       = "pp_mattermost-channel-translatio"
+	userSettingsLanguageOption = "translation_language"
+)
+
+const commandHelp = "###### Channel translations\n" +
+	"* `/translate on` - Translate the new messages of this channel automatically\n" +
+	"* `/translate off` - Stop translating the messages of this channel\n" +
+	"* `/translate status` - Show whether the messages of this channel are translated\n" +
+	"* `/translate languages` - List the languages messages are translated to\n" +
+	"* `/translate set-language <code>` - Set the language you read translations in\n" +
+	"* `/translate primary-language <code|none>` - Set the language the messages of this channel are written in\n" +
+	"* `/translate outbound <on|off>` - Post your messages translated into the primary language of each channel\n" +
+	"* `/translate post <permalink> <code>` - Translate a post into a language, only for you\n" +
+	"* `/translate text <code> <text>` - Translate a text into a language, only for you\n" +
+	"* `/translate help` - Show this help"
+
+func (p *Plugin) getCommand() *model.Command {
+	return &model.Command{
+		Trigger:          commandTrigger,
+		DisplayName:      "Translate",
+		Description:      "Manage channel translations.",
+		AutoComplete:     true,
+		AutoCompleteDesc: "Available commands: on, off, status, languages, set-language, primary-language, outbound, post, text, help",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getCommandAutocompleteData(),
+	}
+}
+
+func getCommandAutocompleteData() *model.AutocompleteData {
+	command := model.NewAutocompleteData(commandTrigger, "[command]", "Available commands: on, off, status, languages, set-language, primary-language, outbound, post, text, help")
+
+	command.AddCommand(model.NewAutocompleteData("on", "", "Translate the new messages of this channel automatically"))
+	command.AddCommand(model.NewAutocompleteData("off", "", "Stop translating the messages of this channel"))
+	command.AddCommand(model.NewAutocompleteData("status", "", "Show whether the messages of this channel are translated"))
+	command.AddCommand(model.NewAutocompleteData("languages", "", "List the languages messages are translated to"))
+
+	setLanguage := model.NewAutocompleteData("set-language", "[code]", "Set the language you read translations in")
+	setLanguage.AddStaticListArgument("Language", true, getLanguageListItems())
+	command.AddCommand(setLanguage)
+
+	primaryLanguage := model.NewAutocompleteData("primary-language", "[code|none]", "Set the language the messages of this channel are written in")
+	primaryLanguage.AddStaticListArgument("Language", true, append(getLanguageListItems(), model.AutocompleteListItem{Item: "none", HelpText: "No primary language"}))
+	command.AddCommand(primaryLanguage)
+
+	outbound := model.NewAutocompleteData("outbound", "[on|off]", "Post your messages translated into the primary language of each channel")
+	outbound.AddStaticListArgument("Mode", true, []model.AutocompleteListItem{
+		{Item: "on", HelpText: "Translate your messages before they are posted"},
+		{Item: "off", HelpText: "Post your messages as you write them"},
+	})
+	command.AddCommand(outbound)
+
+	post := model.NewAutocompleteData("post", "[permalink] [code]", "Translate a post into a language, only for you")
+	post.AddTextArgument("Permalink of the post", "[permalink]", "")
+	post.AddStaticListArgument("Language", true, getLanguageListItems())
+	command.AddCommand(post)
+
+	text := model.NewAutocompleteData("text", "[code] [text]", "Translate a text into a language, only for you")
+	text.AddStaticListArgument("Language", true, getLanguageListItems())
+	text.AddTextArgument("Text to translate", "[text]", "")
+	command.AddCommand(text)
+
+	command.AddCommand(model.NewAutocompleteData("help", "", "Show the available commands"))
+	return command
+}
+
+// getLanguageListItems returns the known languages as autocomplete items, sorted by code.
+func getLanguageListItems() []model.AutocompleteListItem {
+	items := make([]model.AutocompleteListItem, 0, len(languageNames))
+	for code, name := range languageNames {
+		items = append(items, model.AutocompleteListItem{Item: code, HelpText: name})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Item < items[j].Item })
+	return items
+}
+
+// ExecuteCommand runs the /translate command. Every answer is an ephemeral post, so the command
+// works the same in every client.
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	fields := strings.Fields(args.Command)
+	if len(fields) == 0 || fields[0] != "/"+commandTrigger {
+		return p.commandResponse(fmt.Sprintf("Unknown command: %s", args.Command)), nil
+	}
+
+	subcommand := "help"
+	if len(fields) > 1 {
+		subcommand = fields[1]
+	}
+	parameters := fields[min(len(fields), 2):]
+
+	var text string
+	switch subcommand {
+	case "on":
+		text = p.executeToggleCommand(args, true)
+	case "off":
+		text = p.executeToggleCommand(args, false)
+	case "status":
+		text = p.executeStatusCommand(args)
+	case "languages":
+		text = p.executeLanguagesCommand()
+	case "set-language":
+		text = p.executeSetLanguageCommand(args, parameters)
+	case "primary-language":
+		text = p.executePrimaryLanguageCommand(args, parameters)
+	case "outbound":
+		text = p.executeOutboundCommand(args, parameters)
+	case "post":
+		text = p.executePostCommand(args, parameters)
+	case "text":
+		text = p.executeTextCommand(args)
+	case "help":
+		text = commandHelp
+	default:
+		text = fmt.Sprintf("Unknown subcommand %q.\n\n%s", subcommand, commandHelp)
+	}
+
+	if text == "" {
+		return &model.CommandResponse{}, nil
+	}
+	return p.commandResponse(text), nil
+}
+
+func (p *Plugin) commandResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}
+}
+
+func (p *Plugin) executeToggleCommand(args *model.CommandArgs, enabled bool) string {
+	channel, err := p.pluginAPI.Channel.Get(args.ChannelId)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to get channel", "channel_id", args.ChannelId, "error", err.Error())
+		return "Failed to get the channel."
+	}
+
+	if !p.canManageChannelTranslations(args.UserId, channel) {
+		return "You don't have permission to manage the translations of this channel."
+	}
+
+	if err := p.toggleChannelTranslations(channel.Id, args.UserId, enabled); err != nil {
+		p.pluginAPI.Log.Error("Failed to set channel translations", "channel_id", channel.Id, "error", err.Error())
+		return "Failed to update the translations of this channel."
+	}
+
+	if enabled {
+		return "New messages of this channel will be translated."
+	}
+	return "Messages of this channel will no longer be translated."
+}
+
+func (p *Plugin) executeStatusCommand(args *model.CommandArgs) string {
+	if !p.pluginAPI.User.HasPermissionToChannel(args.UserId, args.ChannelId, model.PermissionReadChannel) {
+		return "You don't have permission to read this channel."
+	}
+
+	enabled, err := p.isChannelTranslationEnabled(args.ChannelId)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to get channel translation status", "channel_id", args.ChannelId, "error", err.Error())
+		return "Failed to get the translation status of this channel."
+	}
+
+	switch {
+	case !p.getConfiguration().EnableTranslations:
+		return "Translations are disabled on this server."
+	case !enabled:
+		return "Messages of this channel are not translated."
+	case p.isAutoTranslationPaused(args.ChannelId):
+		return "Translations of this channel are paused because the monthly budget was exceeded."
+	default:
+		return fmt.Sprintf("Messages of this channel are translated to %s.", p.formatLanguages(p.getChannelTranslationLanguages(args.ChannelId)))
+	}
+}
+
+func (p *Plugin) executeLanguagesCommand() string {
+	return fmt.Sprintf("Messages are translated to %s.", p.formatLanguages(p.getTranslationLanguages()))
+}
+
+// formatLanguages returns the languages as a readable list of names and codes.
+func (p *Plugin) formatLanguages(languages []string) string {
+	names := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		names = append(names, fmt.Sprintf("%s (`%s`)", p.getLanguageName(lang), lang))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *Plugin) executeSetLanguageCommand(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) != 1 {
+		return "Usage: `/translate set-language <code>`"
+	}
+
+	lang := parameters[0]
+	if !p.isSelectableLanguage(lang) {
+		return fmt.Sprintf("Unsupported language `%s`. Messages are translated to %s.", lang, p.formatLanguages(p.getTranslationLanguages()))
+	}
+
+	if err := p.saveUserTranslationLanguage(args.UserId, lang); err != nil {
+		p.pluginAPI.Log.Error("Failed to save user translation language", "user_id", args.UserId, "error", err.Error())
+		return "Failed to save your translation language."
+	}
+
+	// Keep the user settings of the webapp in sync
+	appErr := p.API.UpdatePreferencesForUser(args.UserId, []model.Preference{{
+		UserId:   args.UserId,
+		Category: userSettingsCategory,
+		Name:     userSettingsLanguageOption,
+		Value:    lang,
+	}})
+	if appErr != nil {
+		p.pluginAPI.Log.Warn("Failed to update user translation setting", "user_id", args.UserId, "error", appErr.Error())
+	}
+
+	return fmt.Sprintf("You will read translations in %s.", p.getLanguageName(lang))
+}
+
+func (p *Plugin) executePrimaryLanguageCommand(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) != 1 {
+		return "Usage: `/translate primary-language <code|none>`"
+	}
+
+	channel, err := p.pluginAPI.Channel.Get(args.ChannelId)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to get channel", "channel_id", args.ChannelId, "error", err.Error())
+		return "Failed to get the channel."
+	}
+
+	if !p.canManageChannelTranslations(args.UserId, channel) {
+		return "You don't have permission to manage the translations of this channel."
+	}
+
+	lang := parameters[0]
+	if lang == "none" {
+		lang = ""
+	} else if !isSupportedLanguage(lang) {
+		return fmt.Sprintf("Unsupported language `%s`.", lang)
+	}
+
+	if err := p.setChannelPrimaryLanguage(channel.Id, lang); err != nil {
+		p.pluginAPI.Log.Error("Failed to set channel primary language", "channel_id", channel.Id, "error", err.Error())
+		return "Failed to update the primary language of this channel."
+	}
+
+	if lang == "" {
+		return "This channel no longer has a primary language."
+	}
+	return fmt.Sprintf("Messages of this channel are written in %s.", p.getLanguageName(lang))
+}
+
+func (p *Plugin) executeOutboundCommand(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) != 1 || (parameters[0] != "on" && parameters[0] != "off") {
+		return "Usage: `/translate outbound <on|off>`"
+	}
+
+	enabled := parameters[0] == "on"
+	if err := p.setOutboundTranslationEnabled(args.UserId, enabled); err != nil {
+		p.pluginAPI.Log.Error("Failed to set outbound translation", "user_id", args.UserId, "error", err.Error())
+		return "Failed to update your outbound translation."
+	}
+
+	if enabled {
+		return "Your messages will be translated into the primary language of each channel before they are posted."
+	}
+	return "Your messages will be posted as you write them."
+}
+
+// getPostIDFromPermalink returns the ID of the post a permalink points to. A bare post ID is
+// accepted as well.
+func getPostIDFromPermalink(permalink string) string {
+	if parsed, err := url.Parse(permalink); err == nil {
+		permalink = parsed.Path
+	}
+	segments := strings.Split(strings.Trim(permalink, "/"), "/")
+	postID := segments[len(segments)-1]
+	if !model.IsValidId(postID) {
+		return ""
+	}
+	return postID
+}
+
+func (p *Plugin) executePostCommand(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) != 2 {
+		return "Usage: `/translate post <permalink> <code>`"
+	}
+
+	postID := getPostIDFromPermalink(parameters[0])
+	if postID == "" {
+		return "Invalid permalink."
+	}
+	lang := parameters[1]
+
+	post, err := p.pluginAPI.Post.GetPost(postID)
+	if err != nil {
+		return "Failed to get the post."
+	}
+
+	if !p.pluginAPI.User.HasPermissionToChannel(args.UserId, post.ChannelId, model.PermissionReadChannel) {
+		return "You don't have permission to read this post."
+	}
+
+	if !p.isAllowedLanguage(post.ChannelId, lang) {
+		return fmt.Sprintf("Unsupported language `%s`. Messages are translated to %s.", lang, p.formatLanguages(p.getChannelTranslationLanguages(post.ChannelId)))
+	}
+
+	if !p.isTranslatable(post) {
+		return "This post can't be translated."
+	}
+
+	if err := p.checkOnDemandLimits(args.UserId, post.ChannelId, p.countTranslationRequests(post)); err != nil {
+		return p.limitErrorMessage(err)
+	}
+
+	content, err := p.translatePostContent(post, args.UserId, lang)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to translate post", "post_id", post.Id, "error", err.Error())
+		return "Failed to translate the post."
+	}
+
+	if err := p.savePersonalTranslation(args.UserId, post, lang, content, p.newTranslationProvenance(args.UserId)); err != nil {
+		p.pluginAPI.Log.Warn("Failed to save personal translation", "post_id", post.Id, "error", err.Error())
+	}
+
+	return fmt.Sprintf("Translation into %s:\n\n%s", p.getLanguageName(lang), formatTranslatedContent(content))
+}
+
+// formatTranslatedContent returns the translated message, attachments and props of a post as a
+// single text, with a paragraph for each of them.
+func formatTranslatedContent(content TranslatedContent) string {
+	paragraphs := []string{}
+	add := func(texts ...string) {
+		for _, text := range texts {
+			if strings.TrimSpace(text) != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		}
+	}
+
+	add(content.Text)
+	for _, attachment := range content.Attachments {
+		add(attachment.Pretext, attachment.Title, attachment.Text)
+		for _, field := range attachment.Fields {
+			if field.Title != "" && field.Value != "" {
+				add(field.Title + ": " + field.Value)
+			} else {
+				add(field.Title, field.Value)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(content.Props))
+	for path := range content.Props {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		add(content.Props[path])
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// textCommandPattern matches the language and the text of `/translate text`, keeping the text as
+// it was written.
+var textCommandPattern = regexp.MustCompile(`^/` + commandTrigger + `\s+text\s+(\S+)\s+([\s\S]+)$`)
+
+// executeTextCommand translates a text that is not posted anywhere and shows the result only to
+// the user, with a button to share it in the channel. It answers with an empty text when the
+// result was sent.
+func (p *Plugin) executeTextCommand(args *model.CommandArgs) string {
+	matches := textCommandPattern.FindStringSubmatch(strings.TrimSpace(args.Command))
+	if matches == nil {
+		return "Usage: `/translate text <code> <text>`"
+	}
+	lang, text := matches[1], strings.TrimSpace(matches[2])
+
+	if !p.isAllowedLanguage(args.ChannelId, lang) {
+		return fmt.Sprintf("Unsupported language `%s`. Messages are translated to %s.", lang, p.formatLanguages(p.getChannelTranslationLanguages(args.ChannelId)))
+	}
+
+	if err := p.checkOnDemandLimits(args.UserId, args.ChannelId, 1); err != nil {
+		return p.limitErrorMessage(err)
+	}
+
+	translatedText, err := p.translateText(text, args.UserId, args.ChannelId, lang)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to translate text", "error", err.Error())
+		return "Failed to translate the text."
+	}
+
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   fmt.Sprintf("Translation into %s:\n\n%s", p.getLanguageName(lang), translatedText),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Id:    "share",
+			Name:  "Share to channel",
+			Type:  model.PostActionTypeButton,
+			Style: "primary",
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/translation/share", pluginID),
+				Context: map[string]any{
+					"original":    text,
+					"translation": translatedText,
+					"root_id":     args.RootId,
+					"signature":   p.computeShareSignature(args.UserId, args.ChannelId, args.RootId, text, translatedText),
+				},
+			},
+		}},
+	}})
+	p.pluginAPI.Post.SendEphemeralPost(args.UserId, post)
+	return ""
+}
+
+// limitErrorMessage returns what to tell a user whose request failed checking the limits of
+// on-demand translations.
+func (p *Plugin) limitErrorMessage(err error) string {
+	var limited *errRateLimited
+	if errors.As(err, &limited) {
+		return fmt.Sprintf("You have reached the translation limits (%s). Try again in %d seconds.", limited.Error(), int(math.Ceil(limited.retryAfter.Seconds())))
+	}
+	var tooLarge *errRequestTooLarge
+	if errors.As(err, &tooLarge) {
+		return fmt.Sprintf("This can't be translated within the translation limits: %s.", tooLarge.Error())
+	}
+	p.pluginAPI.Log.Error("Failed to check translation limits", "error", err.Error())
+	return "Failed to check the translation limits."
+}
@@ -13,6 +13,31 @@ type Config struct {
 	EnableTranslations      bool   `json:"enableTranslations"`
 	TranslationLanguages    string `json:"translationLanguages"`
 	TranslateSystemMessages bool   `json:"translateSystemMessages"`
+	RetranslateBotEdits     bool   `json:"retranslateBotEdits"`
+	RetranslateWebhookEdits bool   `json:"retranslateWebhookEdits"`
+	RetranslatePluginEdits  bool   `json:"retranslatePluginEdits"`
+
+	// Post types other than regular posts. PostTypeFilter is "allowlist", "denylist" or empty,
+	// PostTypes is a comma separated list and PropPaths holds a "type: path, path" line per type.
+	PostTypeFilter string `json:"postTypeFilter"`
+	PostTypes      string `json:"postTypes"`
+	PropPaths      string `json:"propPaths"`
+
+	// Limits of on-demand translations. Zero means no limit.
+	UserTranslationsPerMinute int `json:"userTranslationsPerMinute"`
+	TeamTranslationsPerMinute int `json:"teamTranslationsPerMinute"`
+	UserDailyTranslationQuota int `json:"userDailyTranslationQuota"`
+	TeamDailyTranslationQuota int `json:"teamDailyTranslationQuota"`
+
+	// Monthly budgets of estimated tokens. Zero means no budget.
+	ChannelMonthlyTokenBudget int     `json:"channelMonthlyTokenBudget"`
+	TeamMonthlyTokenBudget    int     `json:"teamMonthlyTokenBudget"`
+	PauseOverBudget           bool    `json:"pauseOverBudget"`
+	CostPerMillionTokens      float64 `json:"costPerMillionTokens"`
+
+	// MaxFileTranslationSize is the size in KB of the largest file attachment that is translated.
+	// Zero uses the default size.
+	MaxFileTranslationSize int `json:"maxFileTranslationSize"`
 }
 
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
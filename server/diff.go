@@ -0,0 +1,156 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const paragraphSeparator = "\n\n"
+
+// splitParagraphs splits a markdown message into its paragraphs. Blank lines inside fenced code
+// blocks don't end a paragraph, so code blocks are always kept whole.
+func splitParagraphs(message string) []string {
+	paragraphs := []string{}
+	current := []string{}
+	inFence := false
+
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = []string{}
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+
+		if trimmed == "" && !inFence {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return paragraphs
+}
+
+// matchParagraphs pairs the paragraphs that didn't change between two versions of a message,
+// returning the index in the old version of every unchanged paragraph of the new version. It
+// uses the longest common subsequence, so inserted and removed paragraphs don't shift the rest.
+func matchParagraphs(oldParagraphs, newParagraphs []string) map[int]int {
+	lengths := make([][]int, len(oldParagraphs)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newParagraphs)+1)
+	}
+	for i := len(oldParagraphs) - 1; i >= 0; i-- {
+		for j := len(newParagraphs) - 1; j >= 0; j-- {
+			if oldParagraphs[i] == newParagraphs[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	matches := make(map[int]int)
+	for i, j := 0, 0; i < len(oldParagraphs) && j < len(newParagraphs); {
+		switch {
+		case oldParagraphs[i] == newParagraphs[j]:
+			matches[j] = i
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// reuseParagraphTranslations lines up the new paragraphs of a message with the translation of its
+// previous version. It returns the translation of every unchanged paragraph and leaves the ones
+// that changed empty, or false when the previous translation can't be reused at all.
+func reuseParagraphTranslations(oldMessage, oldTranslation string, newParagraphs []string) ([]string, bool) {
+	oldParagraphs := splitParagraphs(oldMessage)
+	oldTranslations := splitParagraphs(oldTranslation)
+	if len(oldParagraphs) != len(oldTranslations) {
+		// The translation can't be lined up with the paragraphs it was made from
+		return nil, false
+	}
+
+	matches := matchParagraphs(oldParagraphs, newParagraphs)
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	translations := make([]string, len(newParagraphs))
+	for i, j := range matches {
+		translations[i] = oldTranslations[j]
+	}
+	return translations, true
+}
+
+// translatePostLanguage translates the post message into a language. When the post was edited,
+// only the paragraphs that changed are translated and the stored translation of the previous
+// message is reused for the rest.
+func (p *Plugin) translatePostLanguage(post, previous *model.Post, requestorID, langCode string) (string, error) {
+	if post.Message == "" {
+		// Posts with only message attachments have no message to translate
+		return "", nil
+	}
+
+	if previous != nil {
+		translation, ok, err := p.translateChangedParagraphs(post, previous, requestorID, langCode)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return translation, nil
+		}
+	}
+
+	return p.translateWithRetry(post.Message, requestorID, post.ChannelId, langCode)
+}
+
+// translateChangedParagraphs translates the paragraphs of the post that changed since the previous
+// version. It returns false when the stored translation of the previous version can't be reused,
+// in which case the whole message has to be translated.
+func (p *Plugin) translateChangedParagraphs(post, previous *model.Post, requestorID, langCode string) (string, bool, error) {
+	stored, err := p.getTranslation(post.Id, langCode)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to get previous translation", "post_id", post.Id, "language", langCode, "error", err.Error())
+		return "", false, nil
+	}
+	if stored == nil || !p.isCurrentTranslation(stored, previous) {
+		return "", false, nil
+	}
+
+	newParagraphs := splitParagraphs(post.Message)
+	translations, ok := reuseParagraphTranslations(previous.Message, stored.Text, newParagraphs)
+	if !ok {
+		return "", false, nil
+	}
+
+	for i, paragraph := range newParagraphs {
+		if translations[i] != "" {
+			continue
+		}
+
+		translation, err := p.translateWithRetry(paragraph, requestorID, post.ChannelId, langCode)
+		if err != nil {
+			return "", false, err
+		}
+		translations[i] = strings.TrimSpace(translation)
+	}
+
+	return strings.Join(translations, paragraphSeparator), true, nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitParagraphs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		message  string
+		expected []string
+	}{
+		"empty message": {
+			message:  "",
+			expected: []string{},
+		},
+		"single paragraph": {
+			message:  "first line\nsecond line",
+			expected: []string{"first line\nsecond line"},
+		},
+		"several paragraphs": {
+			message:  "one\n\ntwo\n\nthree",
+			expected: []string{"one", "two", "three"},
+		},
+		"several blank lines between paragraphs": {
+			message:  "one\n\n\n\ntwo\n",
+			expected: []string{"one", "two"},
+		},
+		"windows line endings": {
+			message:  "one\r\n\r\ntwo",
+			expected: []string{"one", "two"},
+		},
+		"whitespace only line ends paragraph": {
+			message:  "one\n   \ntwo",
+			expected: []string{"one", "two"},
+		},
+		"code fence with blank lines": {
+			message:  "before\n\n```go\nfunc a() {}\n\nfunc b() {}\n```\n\nafter",
+			expected: []string{"before", "```go\nfunc a() {}\n\nfunc b() {}\n```", "after"},
+		},
+		"tilde code fence with blank lines": {
+			message:  "~~~\na\n\n\nb\n~~~",
+			expected: []string{"~~~\na\n\n\nb\n~~~"},
+		},
+		"unclosed code fence keeps the rest together": {
+			message:  "text\n\n```\ncode\n\nmore code",
+			expected: []string{"text", "```\ncode\n\nmore code"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			paragraphs := splitParagraphs(tc.message)
+			if !reflect.DeepEqual(paragraphs, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, paragraphs)
+			}
+		})
+	}
+}
+
+func TestMatchParagraphs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		oldParagraphs []string
+		newParagraphs []string
+		expected      map[int]int
+	}{
+		"no paragraphs": {
+			oldParagraphs: []string{},
+			newParagraphs: []string{},
+			expected:      map[int]int{},
+		},
+		"unchanged": {
+			oldParagraphs: []string{"a", "b", "c"},
+			newParagraphs: []string{"a", "b", "c"},
+			expected:      map[int]int{0: 0, 1: 1, 2: 2},
+		},
+		"changed paragraph": {
+			oldParagraphs: []string{"a", "b", "c"},
+			newParagraphs: []string{"a", "B", "c"},
+			expected:      map[int]int{0: 0, 2: 2},
+		},
+		"inserted paragraph": {
+			oldParagraphs: []string{"a", "b", "c"},
+			newParagraphs: []string{"a", "new", "b", "c"},
+			expected:      map[int]int{0: 0, 2: 1, 3: 2},
+		},
+		"inserted first paragraph": {
+			oldParagraphs: []string{"a", "b"},
+			newParagraphs: []string{"new", "a", "b"},
+			expected:      map[int]int{1: 0, 2: 1},
+		},
+		"removed paragraph": {
+			oldParagraphs: []string{"a", "b", "c"},
+			newParagraphs: []string{"a", "c"},
+			expected:      map[int]int{0: 0, 1: 2},
+		},
+		"removed and inserted paragraphs": {
+			oldParagraphs: []string{"a", "b", "c", "d"},
+			newParagraphs: []string{"b", "c", "new", "d"},
+			expected:      map[int]int{0: 1, 1: 2, 3: 3},
+		},
+		"everything changed": {
+			oldParagraphs: []string{"a", "b"},
+			newParagraphs: []string{"c", "d"},
+			expected:      map[int]int{},
+		},
+		"repeated paragraphs": {
+			oldParagraphs: []string{"a", "a"},
+			newParagraphs: []string{"a", "b", "a"},
+			expected:      map[int]int{0: 0, 2: 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			matches := matchParagraphs(tc.oldParagraphs, tc.newParagraphs)
+			if !reflect.DeepEqual(matches, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, matches)
+			}
+		})
+	}
+}
+
+func TestReuseParagraphTranslations(t *testing.T) {
+	for name, tc := range map[string]struct {
+		oldMessage     string
+		oldTranslation string
+		newMessage     string
+		expected       []string
+		expectedOk     bool
+	}{
+		"changed paragraph": {
+			oldMessage:     "hello\n\nworld",
+			oldTranslation: "hola\n\nmundo",
+			newMessage:     "hello\n\neveryone",
+			expected:       []string{"hola", ""},
+			expectedOk:     true,
+		},
+		"inserted paragraph": {
+			oldMessage:     "hello\n\nworld",
+			oldTranslation: "hola\n\nmundo",
+			newMessage:     "hello\n\nbig\n\nworld",
+			expected:       []string{"hola", "", "mundo"},
+			expectedOk:     true,
+		},
+		"removed paragraph": {
+			oldMessage:     "hello\n\nbig\n\nworld",
+			oldTranslation: "hola\n\ngrande\n\nmundo",
+			newMessage:     "hello\n\nworld",
+			expected:       []string{"hola", "mundo"},
+			expectedOk:     true,
+		},
+		"code fence with blank lines is a single paragraph": {
+			oldMessage:     "run\n\n```\na\n\nb\n```",
+			oldTranslation: "ejecuta\n\n```\na\n\nb\n```",
+			newMessage:     "run this\n\n```\na\n\nb\n```",
+			expected:       []string{"", "```\na\n\nb\n```"},
+			expectedOk:     true,
+		},
+		"translation with another number of paragraphs": {
+			oldMessage:     "hello\n\nworld",
+			oldTranslation: "hola mundo",
+			newMessage:     "hello\n\neveryone",
+			expectedOk:     false,
+		},
+		"nothing unchanged": {
+			oldMessage:     "hello\n\nworld",
+			oldTranslation: "hola\n\nmundo",
+			newMessage:     "goodbye",
+			expectedOk:     false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			translations, ok := reuseParagraphTranslations(tc.oldMessage, tc.oldTranslation, splitParagraphs(tc.newMessage))
+			if ok != tc.expectedOk {
+				t.Fatalf("expected ok: %v, got %v", tc.expectedOk, ok)
+			}
+			if ok && !reflect.DeepEqual(translations, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, translations)
+			}
+		})
+	}
+}
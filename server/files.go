@@ -0,0 +1,206 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	translatedFilesKeyPrefix = "translated_files"
+
+	// defaultMaxFileTranslationSize is the size of the largest file that is translated when no
+	// size is configured.
+	defaultMaxFileTranslationSize = 256 * 1024
+	// maxFileChunkSize is the length of the largest chunk of a text file translated in a single
+	// request. Paragraphs longer than it make a chunk of their own.
+	maxFileChunkSize = 4000
+)
+
+// TranslatedFile is the translation of a file attachment into a language.
+type TranslatedFile struct {
+	FileID string `json:"fileId"`
+	// PostID is the reply the translated file is attached to.
+	PostID     string                 `json:"postId"`
+	UpdateAt   int64                  `json:"updateAt"`
+	Provenance *TranslationProvenance `json:"provenance,omitempty"`
+}
+
+// getMaxFileTranslationSize returns the size in bytes of the largest file that is translated.
+func (c *configuration) getMaxFileTranslationSize() int64 {
+	if c.MaxFileTranslationSize <= 0 {
+		return defaultMaxFileTranslationSize
+	}
+	return int64(c.MaxFileTranslationSize) * 1024
+}
+
+// isTextFile checks if the file is a plain text or Markdown document.
+func isTextFile(info *model.FileInfo) bool {
+	switch strings.ToLower(info.Extension) {
+	case "txt", "md", "markdown":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTranslatableFile checks if the plugin can translate the file.
+func isTranslatableFile(info *model.FileInfo) bool {
+	return isTranscriptFile(info) || isTextFile(info)
+}
+
+// splitFileChunks splits a text file into chunks of whole paragraphs, the same way messages are
+// split, so code blocks are never cut in two.
+func splitFileChunks(content string) []string {
+	chunks := []string{}
+	current := ""
+	for _, paragraph := range splitParagraphs(content) {
+		if current != "" && len(current)+len(paragraphSeparator)+len(paragraph) > maxFileChunkSize {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		if current != "" {
+			current += paragraphSeparator
+		}
+		current += paragraph
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// countFileRequests returns how many requests translating the file takes.
+func countFileRequests(info *model.FileInfo, content string) int {
+	if isTranscriptFile(info) {
+		return countTranscriptBatches(parseTranscript(content))
+	}
+	return len(splitFileChunks(content))
+}
+
+// translateTextFile translates a text file a chunk at a time.
+func (p *Plugin) translateTextFile(content, requestorID, channelID, langCode string) (string, error) {
+	chunks := splitFileChunks(content)
+	translations := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		translation, err := p.translateWithRetry(chunk, requestorID, channelID, langCode)
+		if err != nil {
+			return "", fmt.Errorf("failed to translate chunk: %w", err)
+		}
+		translations = append(translations, strings.TrimSpace(translation))
+	}
+	return strings.Join(translations, paragraphSeparator) + "\n", nil
+}
+
+// translatedFileName returns the name of the translation of a file into a language, as in
+// "meeting.es.vtt".
+func translatedFileName(name, langCode string) string {
+	extension := filepath.Ext(name)
+	return strings.TrimSuffix(name, extension) + "." + langCode + extension
+}
+
+// translatePostFile translates a file attached to the post and attaches the translation to a reply
+// in the thread of the post. The requester is told through an ephemeral post if it fails.
+func (p *Plugin) translatePostFile(post *model.Post, info *model.FileInfo, content, requestorID, langCode string) {
+	var translation string
+	var err error
+	if isTranscriptFile(info) {
+		blocks := parseTranscript(content)
+		err = p.translateTranscript(blocks, requestorID, post.ChannelId, langCode)
+		translation = formatTranscript(blocks)
+	} else {
+		translation, err = p.translateTextFile(content, requestorID, post.ChannelId, langCode)
+	}
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to translate file", "post_id", post.Id, "file_id", info.Id, "language", langCode, "error", err.Error())
+		p.notifyFileTranslationFailed(post, info, requestorID)
+		return
+	}
+
+	translated, err := p.replyWithTranslatedFile(post, info, langCode, translation)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to reply with translated file", "post_id", post.Id, "file_id", info.Id, "language", langCode, "error", err.Error())
+		p.notifyFileTranslationFailed(post, info, requestorID)
+		return
+	}
+
+	translated.Provenance = p.newTranslationProvenance(requestorID)
+	if err := p.saveTranslatedFile(info.Id, langCode, translated); err != nil {
+		p.pluginAPI.Log.Error("Failed to save translated file", "file_id", info.Id, "language", langCode, "error", err.Error())
+	}
+}
+
+// replyWithTranslatedFile uploads the translation of a file attached to the post and attaches it to
+// a reply of the plugin bot in the thread of the post.
+func (p *Plugin) replyWithTranslatedFile(post *model.Post, info *model.FileInfo, langCode, content string) (*TranslatedFile, error) {
+	uploaded, err := p.pluginAPI.File.Upload(strings.NewReader(content), translatedFileName(info.Name, langCode), post.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload translated file: %w", err)
+	}
+
+	rootID := post.RootId
+	if rootID == "" {
+		rootID = post.Id
+	}
+	reply := &model.Post{
+		UserId:    p.botID,
+		ChannelId: post.ChannelId,
+		RootId:    rootID,
+		Message:   fmt.Sprintf("Translation of %s into %s", info.Name, p.getLanguageName(langCode)),
+		FileIds:   model.StringArray{uploaded.Id},
+	}
+	if err := p.pluginAPI.Post.CreatePost(reply); err != nil {
+		return nil, fmt.Errorf("failed to create reply: %w", err)
+	}
+
+	return &TranslatedFile{
+		FileID:   uploaded.Id,
+		PostID:   reply.Id,
+		UpdateAt: model.GetMillis(),
+	}, nil
+}
+
+// notifyFileTranslationFailed tells the requester that a file attached to the post couldn't be
+// translated.
+func (p *Plugin) notifyFileTranslationFailed(post *model.Post, info *model.FileInfo, requestorID string) {
+	p.pluginAPI.Post.SendEphemeralPost(requestorID, &model.Post{
+		UserId:    p.botID,
+		ChannelId: post.ChannelId,
+		RootId:    post.RootId,
+		Message:   fmt.Sprintf("Failed to translate %s.", info.Name),
+	})
+}
+
+func getTranslatedFilesKey(fileID string) string {
+	return fmt.Sprintf("%s_%s", translatedFilesKeyPrefix, fileID)
+}
+
+// saveTranslatedFile atomically adds the translation of a file into a language to the translated
+// files of the file, replacing any earlier translation into the same language.
+func (p *Plugin) saveTranslatedFile(fileID, langCode string, translated *TranslatedFile) error {
+	return p.pluginAPI.KV.SetAtomicWithRetries(getTranslatedFilesKey(fileID), func(oldValue []byte) (interface{}, error) {
+		files := make(map[string]*TranslatedFile)
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &files); err != nil {
+				return nil, err
+			}
+		}
+		files[langCode] = translated
+		return files, nil
+	})
+}
+
+// getTranslatedFiles returns the translations of a file, keyed by language.
+func (p *Plugin) getTranslatedFiles(fileID string) (map[string]*TranslatedFile, error) {
+	files := make(map[string]*TranslatedFile)
+	if err := p.pluginAPI.KV.Get(getTranslatedFilesKey(fileID), &files); err != nil {
+		return nil, fmt.Errorf("failed to get translated files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFileChunks(t *testing.T) {
+	// half fills a chunk together with another one and the separator between them
+	half := strings.Repeat("a", (maxFileChunkSize-len(paragraphSeparator))/2)
+	other := strings.Repeat("b", maxFileChunkSize-len(paragraphSeparator)-len(half))
+	long := strings.Repeat("c", maxFileChunkSize+1)
+
+	for name, tc := range map[string]struct {
+		content  string
+		expected []string
+	}{
+		"empty file": {
+			content:  "",
+			expected: []string{},
+		},
+		"short file": {
+			content:  "one\n\ntwo\n",
+			expected: []string{"one\n\ntwo"},
+		},
+		"paragraphs filling a chunk exactly": {
+			content:  half + "\n\n" + other,
+			expected: []string{half + paragraphSeparator + other},
+		},
+		"paragraphs one character over a chunk": {
+			content:  half + "\n\n" + other + "b",
+			expected: []string{half, other + "b"},
+		},
+		"paragraph longer than a chunk": {
+			content:  "intro\n\n" + long + "\n\noutro",
+			expected: []string{"intro", long, "outro"},
+		},
+		"code block is not cut in two": {
+			content:  half + "\n\n```\n" + other + "\n\nmore\n```",
+			expected: []string{half, "```\n" + other + "\n\nmore\n```"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			chunks := splitFileChunks(tc.content)
+			if !reflect.DeepEqual(chunks, tc.expected) {
+				t.Errorf("expected %d chunks, got %d", len(tc.expected), len(chunks))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"errors"
 	"strings"
 	"sync"
 
@@ -11,116 +12,292 @@ import (
 	"github.com/mattermost/mattermost/server/public/plugin"
 )
 
-// isSystemMessage checks if a post is a system message
+// isSystemMessage checks if a post is a system message. Posts with message attachments are
+// regular posts.
 func isSystemMessage(post *model.Post) bool {
-	return post.Type != "" && post.Type != "custom_translation"
+	return post.Type != "" && post.Type != legacyTranslationPostType && post.Type != model.PostTypeSlackAttachment
 }
 
-func (p *Plugin) MessageHasBeenUpdated(c *plugin.Context, post *model.Post, oldPost *model.Post) {
-	// Only update the translation if the update is made by a user (not a plugin or bot)
+// isTranslatable checks if the post has text that the plugin is configured to translate
+func (p *Plugin) isTranslatable(post *model.Post) bool {
+	if !p.hasTranslatableContent(post) {
+		return false
+	}
+	return p.isTranslatedPostType(post.Type)
+}
+
+// shouldAutoTranslate checks if the post belongs to a channel where messages are translated
+// automatically
+func (p *Plugin) shouldAutoTranslate(post *model.Post) bool {
+	if !p.getConfiguration().EnableTranslations || !p.isTranslatable(post) {
+		return false
+	}
+	return p.isChannelAutoTranslated(post.ChannelId)
+}
+
+// isChannelAutoTranslated checks if the channel has translations turned on and is within its
+// translation budget. Channels over it are not translated until the next month.
+func (p *Plugin) isChannelAutoTranslated(channelID string) bool {
+	enabled, err := p.isChannelTranslationEnabled(channelID)
+	return err == nil && enabled && !p.isAutoTranslationPaused(channelID)
+}
+
+// shouldRetranslateEdit checks if an edit of the post should be translated again. Edits made
+// through a user session always are, while edits from bots, webhooks and plugins follow the
+// configured rules.
+func (p *Plugin) shouldRetranslateEdit(c *plugin.Context, post *model.Post) bool {
+	if !p.shouldAutoTranslate(post) {
+		return false
+	}
+
 	if c.SessionId != "" {
-		p.MessageHasBeenPosted(c, post)
+		return true
+	}
+
+	config := p.getConfiguration()
+	if post.GetProp(model.PostPropsFromWebhook) == "true" {
+		return config.RetranslateWebhookEdits
+	}
+
+	if user, err := p.pluginAPI.User.Get(post.UserId); err == nil && user.IsBot {
+		return config.RetranslateBotEdits
 	}
+
+	return config.RetranslatePluginEdits
 }
 
-func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
-	if !p.getConfiguration().EnableTranslations {
-		return post, ""
+func (p *Plugin) MessageWillBeUpdated(c *plugin.Context, newPost, oldPost *model.Post) (*model.Post, string) {
+	newPost = p.protectTranslationProps(newPost, oldPost)
+	newPost = protectOriginalMessage(newPost, oldPost)
+
+	// The plugin writes back the whole post it read, so a write of the plugin with another text
+	// than the stored post would undo an edit made in the meantime
+	if isPluginUpdate(newPost, oldPost) && !p.hasSameContent(newPost, oldPost) {
+		return nil, staleTranslationRejection
 	}
 
-	// Skip empty messages
-	if post.Message == "" {
-		return post, ""
+	// The server keeps the type of the stored post on updates, so the type that earlier versions of
+	// the plugin replaced is given back here, once the plugin has migrated the translations
+	if isPluginUpdate(newPost, oldPost) && newPost.Type == legacyTranslationPostType && newPost.GetProp(translationsProp) == nil {
+		newPost = newPost.Clone()
+		newPost.Type = ""
 	}
 
-	// Skip if already translated
-	if _, ok := post.Props["translations"]; ok {
-		return post, ""
+	if p.hasSameContent(newPost, oldPost) || isPluginUpdate(newPost, oldPost) {
+		return newPost, ""
+	}
+
+	statuses := getPostTranslationStatuses(newPost)
+	if len(statuses) == 0 && newPost.GetProp(translationsProp) == nil {
+		return newPost, ""
+	}
+
+	// The translations of the previous message are outdated from now on, so clients stop showing
+	// them right away. Languages that are translated again go back to pending.
+	updatedPost := newPost.Clone()
+	for lang := range statuses {
+		statuses[lang] = LanguageStatus{Status: TranslationStatusSkipped, Reason: "message was edited"}
+	}
+	if p.shouldRetranslateEdit(c, newPost) {
+		for _, lang := range p.getTranslationLanguages() {
+			statuses[lang] = LanguageStatus{Status: TranslationStatusPending}
+		}
+	}
+	updatedPost.DelProp(translationsProp)
+	updatedPost.AddProp(translationStatusProp, statuses)
+	p.signTranslationProps(updatedPost)
+	return updatedPost, ""
+}
+
+func (p *Plugin) MessageHasBeenUpdated(c *plugin.Context, post *model.Post, oldPost *model.Post) {
+	// Skip the plugin's own updates and edits that are not translated again
+	if p.hasSameContent(post, oldPost) || isPluginUpdate(post, oldPost) || !p.shouldRetranslateEdit(c, post) {
+		return
 	}
 
-	// Skip system messages if translateSystemMessages is disabled
-	if isSystemMessage(post) && !p.getConfiguration().TranslateSystemMessages {
+	p.translatePost(post, oldPost, post.UserId, requestedByAuto, p.getTranslationLanguages())
+}
+
+func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
+	// Only the plugin writes translations, so the ones sent along with a new post are made up
+	post = stripTranslationProps(post)
+	post = p.stripUnsignedOriginalMessage(post)
+
+	if !p.getConfiguration().EnableTranslations {
 		return post, ""
 	}
 
-	// Check if translations are enabled for this channel
-	enabled, err := p.isChannelTranslationEnabled(post.ChannelId)
-	if err != nil || !enabled {
+	// Authors in outbound mode write in their language and post in the channel's one
+	post, rejection := p.translateOutboundPost(post)
+	if rejection != "" {
+		return nil, rejection
+	}
+
+	if !p.shouldAutoTranslate(post) {
 		return post, ""
 	}
 
+	// The translation state lives in the props, so the post keeps its own type and rendering.
+	// Every language starts as pending so clients can tell in-flight translations from failed ones.
 	newPost := post.Clone()
-	newPost.Type = "custom_translation"
+	statuses := make(map[string]LanguageStatus)
+	for _, lang := range p.getTranslationLanguages() {
+		statuses[lang] = LanguageStatus{Status: TranslationStatusPending}
+	}
+	newPost.AddProp(translationStatusProp, statuses)
+	p.signTranslationProps(newPost)
 	return newPost, ""
 }
 
 func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
-	// Skip if global translations are disabled
 	if !p.getConfiguration().EnableTranslations {
 		return
 	}
 
-	// Skip empty messages
-	if post.Message == "" {
-		return
+	// The header and purpose of translated channels are translated again whenever they change
+	if isChannelInfoChange(post) && p.isChannelAutoTranslated(post.ChannelId) {
+		go p.translateChannelInfo(post.ChannelId, post.UserId, requestedByAuto)
 	}
 
-	// Skip system messages if translateSystemMessages is disabled
-	if isSystemMessage(post) && !p.getConfiguration().TranslateSystemMessages {
+	if !p.shouldAutoTranslate(post) {
 		return
 	}
 
-	// Check if translations are enabled for this channel
-	enabled, err := p.isChannelTranslationEnabled(post.ChannelId)
-	if err != nil || !enabled {
-		return
+	p.translatePost(post, nil, post.UserId, requestedByAuto, p.getTranslationLanguages())
+}
+
+// translateWithRetry translates the text, retrying failed attempts.
+func (p *Plugin) translateWithRetry(message, requestorID, channelID, langCode string) (string, error) {
+	var err error
+	for maxRetry := 10; maxRetry > 0; maxRetry-- {
+		var result string
+		result, err = p.translateText(message, requestorID, channelID, langCode)
+		if err == nil {
+			return result, nil
+		}
 	}
+	return "", err
+}
 
-	// Get configured languages or use default
-	languages := p.getConfiguration().TranslationLanguages
-	if languages == "" {
-		languages = "english"
+// translatePostContent translates the post message, the text of its attachments and the props
+// configured for its type into a language, for the translations made on demand.
+func (p *Plugin) translatePostContent(post *model.Post, requestorID, langCode string) (TranslatedContent, error) {
+	var content TranslatedContent
+	var err error
+	if post.Message != "" {
+		if content.Text, err = p.translateText(post.Message, requestorID, post.ChannelId, langCode); err != nil {
+			return TranslatedContent{}, err
+		}
+	}
+	if content.Attachments, err = p.translateAttachments(post, requestorID, langCode); err != nil {
+		return TranslatedContent{}, err
+	}
+	if content.Props, err = p.translateProps(post, requestorID, langCode); err != nil {
+		return TranslatedContent{}, err
 	}
+	return content, nil
+}
 
+// translatePost translates the post message, the text of its attachments and the props configured
+// for its type into the given languages. When the post was edited,
+// previous is the post before the edit and only the paragraphs that changed are translated again.
+// Each language is pushed to the channel members through a websocket event as soon as it
+// completes, while the post itself is written once with all the results. Translations are
+// discarded if the post message changes in the meantime. requestedBy is recorded in the provenance
+// of the translations.
+func (p *Plugin) translatePost(post, previous *model.Post, requestorID, requestedBy string, languages []string) {
 	waitGroup := sync.WaitGroup{}
 	mutex := sync.Mutex{}
-	translations := make(map[string]interface{})
+	translations := make(map[string]TranslatedContent)
+	statuses := make(map[string]LanguageStatus)
 	waitlist := make(chan struct{}, 3)
 
-	for _, language := range strings.Split(languages, ",") {
+	for _, language := range languages {
 		waitlist <- struct{}{}
 		waitGroup.Add(1)
 		go func(langCode string) {
 			defer waitGroup.Done()
-			maxRetry := 10
-
-			for {
-				result, err := p.translateText(post.Message, post.UserId, langCode)
-				if err != nil {
-					maxRetry--
-					if maxRetry == 0 {
-						break
-					}
-					continue
-				}
+			defer func() { <-waitlist }()
+
+			result, err := p.translatePostLanguage(post, previous, requestorID, langCode)
+			var translatedAttachments []TranslatedAttachment
+			if err == nil {
+				translatedAttachments, err = p.translateAttachments(post, requestorID, langCode)
+			}
+			var translatedProps map[string]string
+			if err == nil {
+				translatedProps, err = p.translateProps(post, requestorID, langCode)
+			}
+
+			var status LanguageStatus
+			switch {
+			case err != nil:
+				p.pluginAPI.Log.Warn("Failed to translate post", "post_id", post.Id, "language", langCode, "error", err.Error())
+				status = LanguageStatus{Status: TranslationStatusFailed, Reason: err.Error()}
+				result = ""
+			case strings.TrimSpace(result) == strings.TrimSpace(post.Message) && isSameAttachmentText(post, translatedAttachments) && p.isSamePropText(post, translatedProps):
+				// The message is already written in this language
+				status = LanguageStatus{Status: TranslationStatusSkipped, Reason: "message is already in the requested language"}
+				result = ""
+			default:
+				status = LanguageStatus{Status: TranslationStatusDone}
+			}
 
-				mutex.Lock()
-				translations[langCode] = result
-				// Store translations in post props
-				if post.Props == nil {
-					post.Props = make(model.StringInterface)
+			mutex.Lock()
+			statuses[langCode] = status
+			if status.Status == TranslationStatusDone {
+				translations[langCode] = TranslatedContent{
+					Text:        result,
+					Attachments: translatedAttachments,
+					Props:       translatedProps,
 				}
-				post.Props["translations"] = translations
-				// Ensure the post type remains as custom_translation
-				post.Type = "custom_translation"
-				_ = p.pluginAPI.Post.UpdatePost(post)
-				mutex.Unlock()
-				<-waitlist
-				break
 			}
+			mutex.Unlock()
+
+			p.publishTranslationProgress(post, langCode, status, result)
 		}(language)
 	}
 
 	waitGroup.Wait()
 	close(waitlist)
+
+	provenance := p.newTranslationProvenance(requestedBy)
+	err := p.updatePostTranslations(post, func(current *model.Post) error {
+		removed := []string{}
+		currentStatuses := getPostTranslationStatuses(current)
+		for lang, status := range statuses {
+			currentStatuses[lang] = status
+			if _, ok := translations[lang]; !ok {
+				removed = append(removed, lang)
+			}
+		}
+
+		if err := p.saveTranslations(current, translations, provenance); err != nil {
+			return err
+		}
+		if err := p.deleteTranslations(current.Id, removed); err != nil {
+			return err
+		}
+
+		current.AddProp(translationStatusProp, currentStatuses)
+		return nil
+	})
+	if errors.Is(err, errStaleTranslation) {
+		p.pluginAPI.Log.Debug("Discarding translations of outdated message", "post_id", post.Id)
+	} else if err != nil {
+		p.pluginAPI.Log.Error("Failed to update post with translations", "post_id", post.Id, "error", err.Error())
+	}
+}
+
+// publishTranslationProgress notifies the channel members that a language of the post finished
+// translating, so clients can show it before the post is updated.
+func (p *Plugin) publishTranslationProgress(post *model.Post, langCode string, status LanguageStatus, translation string) {
+	p.pluginAPI.Frontend.PublishWebSocketEvent(translationProgressEvent, map[string]any{
+		"post_id":     post.Id,
+		"message":     post.Message,
+		"language":    langCode,
+		"status":      status.Status,
+		"reason":      status.Reason,
+		"translation": translation,
+	}, &model.WebsocketBroadcast{ChannelId: post.ChannelId})
 }
@@ -0,0 +1,125 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+func TestMessageWillBeUpdated(t *testing.T) {
+	p := &Plugin{signingKey: []byte("signing key")}
+	done := map[string]LanguageStatus{"es": {Status: TranslationStatusDone}}
+	legacy := map[string]interface{}{"es": "Hola"}
+
+	for name, tc := range map[string]struct {
+		oldPost              func() *model.Post
+		newPost              func() *model.Post
+		expectedRejection    string
+		expectedMessage      string
+		expectedType         string
+		expectedStatuses     map[string]LanguageStatus
+		expectedTranslations interface{}
+	}{
+		"update by a client keeping the message": {
+			oldPost: func() *model.Post { return newTranslatedPost(p, 1, done, legacy, true) },
+			newPost: func() *model.Post {
+				return newTranslatedPost(p, 1, done, map[string]interface{}{"es": "FORGED"}, true)
+			},
+			expectedMessage:      "Hello",
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"edit by a client": {
+			oldPost: func() *model.Post { return newTranslatedPost(p, 1, done, legacy, true) },
+			newPost: func() *model.Post {
+				post := newTranslatedPost(p, 1, done, legacy, true)
+				post.Message = "Hello again"
+				return post
+			},
+			expectedMessage:  "Hello again",
+			expectedStatuses: map[string]LanguageStatus{"es": {Status: TranslationStatusSkipped, Reason: "message was edited"}},
+		},
+		"edit of a post without translations": {
+			oldPost:          func() *model.Post { return &model.Post{Id: "post1", Message: "Hello"} },
+			newPost:          func() *model.Post { return &model.Post{Id: "post1", Message: "Hello again"} },
+			expectedMessage:  "Hello again",
+			expectedStatuses: map[string]LanguageStatus{},
+		},
+		"update by the plugin": {
+			oldPost:          func() *model.Post { return newTranslatedPost(p, 1, nil, nil, true) },
+			newPost:          func() *model.Post { return newTranslatedPost(p, 2, done, nil, true) },
+			expectedMessage:  "Hello",
+			expectedStatuses: done,
+		},
+		"update by the plugin of an outdated message": {
+			oldPost: func() *model.Post {
+				post := newTranslatedPost(p, 1, nil, nil, true)
+				post.Message = "Hello again"
+				return post
+			},
+			newPost:           func() *model.Post { return newTranslatedPost(p, 2, done, nil, true) },
+			expectedRejection: staleTranslationRejection,
+		},
+		"migration of a legacy post by the plugin": {
+			oldPost: func() *model.Post {
+				post := newTranslatedPost(p, 0, nil, legacy, false)
+				post.Type = legacyTranslationPostType
+				return post
+			},
+			newPost: func() *model.Post {
+				post := newTranslatedPost(p, 1, done, nil, true)
+				post.Type = legacyTranslationPostType
+				return post
+			},
+			expectedMessage:  "Hello",
+			expectedStatuses: done,
+		},
+		"update by a client of a legacy post": {
+			oldPost: func() *model.Post {
+				post := newTranslatedPost(p, 0, nil, legacy, false)
+				post.Type = legacyTranslationPostType
+				return post
+			},
+			newPost: func() *model.Post {
+				post := newTranslatedPost(p, 0, nil, nil, false)
+				post.Type = legacyTranslationPostType
+				return post
+			},
+			expectedMessage:      "Hello",
+			expectedType:         legacyTranslationPostType,
+			expectedStatuses:     map[string]LanguageStatus{},
+			expectedTranslations: legacy,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post, rejection := p.MessageWillBeUpdated(&plugin.Context{}, tc.newPost(), tc.oldPost())
+			if rejection != tc.expectedRejection {
+				t.Fatalf("expected rejection %q, got %q", tc.expectedRejection, rejection)
+			}
+			if tc.expectedRejection != "" {
+				if post != nil {
+					t.Errorf("expected no post, got %+v", post)
+				}
+				return
+			}
+
+			if post.Message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, post.Message)
+			}
+			if post.Type != tc.expectedType {
+				t.Errorf("expected type %q, got %q", tc.expectedType, post.Type)
+			}
+			if statuses := getPostTranslationStatuses(post); !reflect.DeepEqual(statuses, tc.expectedStatuses) {
+				t.Errorf("expected statuses %v, got %v", tc.expectedStatuses, statuses)
+			}
+			if translations := post.GetProp(translationsProp); !reflect.DeepEqual(normalizeProp(translations), normalizeProp(tc.expectedTranslations)) {
+				t.Errorf("expected translations %v, got %v", tc.expectedTranslations, translations)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+const (
+	postsMigrationKey = "migration_posts_version"
+	// postsMigrationVersion is increased every time migratePost learns to migrate something new:
+	// 1 moved the translations from the post props into the translation store, 2 restored the post
+	// type that was replaced with custom_translation.
+	postsMigrationVersion = 2
+	migrationPostsPerPage = 200
+)
+
+// needsMigration checks if the post still carries the translation state written by earlier versions
+// of the plugin.
+func needsMigration(post *model.Post) bool {
+	return post.GetProp(translationsProp) != nil || post.Type == legacyTranslationPostType
+}
+
+// migratePost moves the translations that earlier versions of the plugin kept in the post props
+// into the translation store. MessageWillBeUpdated then gives back the post its own type. The type
+// the post had before it was replaced with custom_translation is not known, so posts become regular
+// posts again.
+func (p *Plugin) migratePost(post *model.Post) error {
+	if !needsMigration(post) {
+		return nil
+	}
+
+	return p.updatePostTranslations(post, func(current *model.Post) error {
+		translations := make(map[string]TranslatedContent)
+		statuses := getPostTranslationStatuses(current)
+		for lang, text := range getPostTranslations(current) {
+			translation, ok := text.(string)
+			if !ok {
+				continue
+			}
+			translations[lang] = TranslatedContent{Text: translation}
+			if _, ok := statuses[lang]; !ok {
+				statuses[lang] = LanguageStatus{Status: TranslationStatusDone}
+			}
+		}
+
+		if err := p.saveTranslations(current, translations, nil); err != nil {
+			return err
+		}
+
+		current.DelProp(translationsProp)
+		current.AddProp(translationStatusProp, statuses)
+		return nil
+	})
+}
+
+// runPostsMigration migrates the posts of every channel that ever had translations enabled. It
+// runs once per cluster and version; posts translated on demand in other channels are migrated
+// when their translations are read.
+func (p *Plugin) runPostsMigration() {
+	var version int
+	if err := p.pluginAPI.KV.Get(postsMigrationKey, &version); err != nil {
+		p.pluginAPI.Log.Error("Failed to check posts migration", "error", err.Error())
+		return
+	}
+	if version >= postsMigrationVersion {
+		return
+	}
+
+	mutex, err := cluster.NewMutex(p.API, postsMigrationKey+"_lock")
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to create posts migration lock", "error", err.Error())
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Another server may have finished the migration while we waited for the lock
+	if err := p.pluginAPI.KV.Get(postsMigrationKey, &version); err != nil || version >= postsMigrationVersion {
+		return
+	}
+
+	migrated, err := p.migrateChannelsPosts()
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to migrate posts", "migrated_posts", migrated, "error", err.Error())
+		return
+	}
+
+	if _, err := p.pluginAPI.KV.Set(postsMigrationKey, postsMigrationVersion); err != nil {
+		p.pluginAPI.Log.Error("Failed to mark posts migration as done", "error", err.Error())
+		return
+	}
+	p.pluginAPI.Log.Info("Migrated posts", "version", postsMigrationVersion, "migrated_posts", migrated)
+}
+
+func (p *Plugin) migrateChannelsPosts() (int, error) {
+	channelIDs, err := p.listTranslationChannels(false)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, channelID := range channelIDs {
+		for page := 0; ; page++ {
+			postList, err := p.pluginAPI.Post.GetPostsForChannel(channelID, page, migrationPostsPerPage)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to get posts of channel %s: %w", channelID, err)
+			}
+			if len(postList.Order) == 0 {
+				break
+			}
+
+			for _, postID := range postList.Order {
+				post := postList.Posts[postID]
+				if post == nil || !needsMigration(post) {
+					continue
+				}
+
+				if err := p.migratePost(post); err != nil && !errors.Is(err, errStaleTranslation) {
+					return migrated, fmt.Errorf("failed to migrate post %s: %w", post.Id, err)
+				}
+				migrated++
+			}
+		}
+	}
+	return migrated, nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	channelPrimaryLanguageKeyPrefix = "channel_primary_language"
+
+	// userSettingsOutboundOption is the user setting that turns on the outbound translation of the
+	// user's messages.
+	userSettingsOutboundOption = "outbound_translation"
+	outboundTranslationOn      = "on"
+)
+
+func getChannelPrimaryLanguageKey(channelID string) string {
+	return fmt.Sprintf("%s_%s", channelPrimaryLanguageKeyPrefix, channelID)
+}
+
+// setChannelPrimaryLanguage sets the language the messages of the channel are written in. An empty
+// language removes it.
+func (p *Plugin) setChannelPrimaryLanguage(channelID, langCode string) error {
+	key := getChannelPrimaryLanguageKey(channelID)
+	if langCode == "" {
+		if err := p.pluginAPI.KV.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete channel primary language: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := p.pluginAPI.KV.Set(key, langCode); err != nil {
+		return fmt.Errorf("failed to set channel primary language: %w", err)
+	}
+	return nil
+}
+
+// getChannelPrimaryLanguage returns the language the messages of the channel are written in, or
+// an empty string if the channel has none.
+func (p *Plugin) getChannelPrimaryLanguage(channelID string) (string, error) {
+	var langCode string
+	if err := p.pluginAPI.KV.Get(getChannelPrimaryLanguageKey(channelID), &langCode); err != nil {
+		return "", fmt.Errorf("failed to get channel primary language: %w", err)
+	}
+	return langCode, nil
+}
+
+// isOutboundTranslationEnabled checks if the messages of the user are translated into the primary
+// language of the channel before they are posted. The mode is a user setting, so it is kept in the
+// user preferences.
+func (p *Plugin) isOutboundTranslationEnabled(userID string) bool {
+	preference, appErr := p.API.GetPreferenceForUser(userID, userSettingsCategory, userSettingsOutboundOption)
+	return appErr == nil && preference.Value == outboundTranslationOn
+}
+
+// setOutboundTranslationEnabled turns the outbound translation of the user's messages on or off.
+func (p *Plugin) setOutboundTranslationEnabled(userID string, enabled bool) error {
+	value := "off"
+	if enabled {
+		value = outboundTranslationOn
+	}
+
+	appErr := p.API.UpdatePreferencesForUser(userID, []model.Preference{{
+		UserId:   userID,
+		Category: userSettingsCategory,
+		Name:     userSettingsOutboundOption,
+		Value:    value,
+	}})
+	if appErr != nil {
+		return fmt.Errorf("failed to set outbound translation: %w", appErr)
+	}
+	return nil
+}
+
+// translateOutboundPost translates the message of a post written by a user in outbound mode into
+// the primary language of the channel, keeping the original in the props. Posts over the limits
+// of on-demand translations are rejected with the reason, so they don't reach the channel in the
+// wrong language. The post is returned as it was when the translation fails, and its author is
+// told.
+func (p *Plugin) translateOutboundPost(post *model.Post) (*model.Post, string) {
+	if post.Message == "" || isSystemMessage(post) || post.GetProp(originalMessageProp) != nil {
+		return post, ""
+	}
+	if post.GetProp(model.PostPropsFromWebhook) == "true" || post.UserId == p.botID {
+		return post, ""
+	}
+
+	langCode, err := p.getChannelPrimaryLanguage(post.ChannelId)
+	if err != nil || langCode == "" {
+		return post, ""
+	}
+
+	if !p.isOutboundTranslationEnabled(post.UserId) {
+		return post, ""
+	}
+
+	if err := p.checkOnDemandLimits(post.UserId, post.ChannelId, 1); err != nil {
+		return nil, p.limitErrorMessage(err)
+	}
+
+	translation, err := p.translateText(post.Message, post.UserId, post.ChannelId, langCode)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to translate outbound message", "user_id", post.UserId, "error", err.Error())
+		p.pluginAPI.Post.SendEphemeralPost(post.UserId, &model.Post{
+			UserId:    p.botID,
+			ChannelId: post.ChannelId,
+			RootId:    post.RootId,
+			Message:   "Your message couldn't be translated, so it was posted as you wrote it.",
+		})
+		return post, ""
+	}
+
+	// The message is already written in the primary language
+	if strings.TrimSpace(translation) == strings.TrimSpace(post.Message) {
+		return post, ""
+	}
+
+	translatedPost := post.Clone()
+	translatedPost.Message = translation
+	p.signOriginalMessage(translatedPost, post.Message)
+	return translatedPost, ""
+}
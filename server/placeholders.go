@@ -0,0 +1,52 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+)
+
+// placeholderPatterns match the parts of a message that must be kept as they are in its
+// translations: code, links, mentions, hashtags and emojis. Code is matched first so nothing
+// inside it is taken for another placeholder.
+var placeholderPatterns = []*regexp.Regexp{
+	regexp.MustCompile("(?s)```.*?```"),
+	regexp.MustCompile("`[^`\n]+`"),
+	regexp.MustCompile(`https?://[^\s<>()\[\]]+`),
+	regexp.MustCompile(`(?:^|\s)(@[a-zA-Z0-9._\-]+[a-zA-Z0-9_\-])`),
+	regexp.MustCompile(`(?:^|\s)(~[a-z0-9_\-]+)`),
+	regexp.MustCompile(`(?:^|\s)(#[a-zA-Z][\w.\-]*\w)`),
+	regexp.MustCompile(`:[a-z0-9_+\-]+:`),
+}
+
+// extractPlaceholders returns how many times each placeholder appears in the message.
+func extractPlaceholders(message string) map[string]int {
+	placeholders := make(map[string]int)
+	for _, pattern := range placeholderPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(message, -1) {
+			placeholder := match[len(match)-1]
+			placeholders[placeholder]++
+		}
+		message = pattern.ReplaceAllString(message, " ")
+	}
+	return placeholders
+}
+
+// validatePlaceholders returns a warning for every placeholder of the original message that the
+// translation lost or altered.
+func validatePlaceholders(original, translation string) []string {
+	expected := extractPlaceholders(original)
+	found := extractPlaceholders(translation)
+
+	warnings := []string{}
+	for _, placeholder := range slices.Sorted(maps.Keys(expected)) {
+		if missing := expected[placeholder] - found[placeholder]; missing > 0 {
+			warnings = append(warnings, fmt.Sprintf("%q is missing from the translation", placeholder))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPlaceholders(t *testing.T) {
+	for name, tc := range map[string]struct {
+		message  string
+		expected map[string]int
+	}{
+		"plain text": {
+			message:  "Nothing to keep here.",
+			expected: map[string]int{},
+		},
+		"mentions, channels, hashtags and emojis": {
+			message: "@john.doe please check ~town-square for #release-notes :tada:",
+			expected: map[string]int{
+				"@john.doe":      1,
+				"~town-square":   1,
+				"#release-notes": 1,
+				":tada:":         1,
+			},
+		},
+		"mention at the end of a sentence": {
+			message:  "Thanks @anna.",
+			expected: map[string]int{"@anna": 1},
+		},
+		"email address is not a mention": {
+			message:  "Write to support@example.com",
+			expected: map[string]int{},
+		},
+		"links": {
+			message:  "See https://example.com/docs?page=2 and (http://example.org)",
+			expected: map[string]int{"https://example.com/docs?page=2": 1, "http://example.org": 1},
+		},
+		"repeated placeholders are counted": {
+			message:  ":+1: @bob and again @bob :+1:",
+			expected: map[string]int{"@bob": 2, ":+1:": 2},
+		},
+		"inline code hides what is inside it": {
+			message:  "Run `notify @all :x:` now",
+			expected: map[string]int{"`notify @all :x:`": 1},
+		},
+		"code fence with blank lines hides what is inside it": {
+			message:  "Example:\n```\n@here\n\n#not-a-tag\n```\nby @carl",
+			expected: map[string]int{"```\n@here\n\n#not-a-tag\n```": 1, "@carl": 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			placeholders := extractPlaceholders(tc.message)
+			if !reflect.DeepEqual(placeholders, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, placeholders)
+			}
+		})
+	}
+}
+
+func TestValidatePlaceholders(t *testing.T) {
+	for name, tc := range map[string]struct {
+		original    string
+		translation string
+		expected    []string
+	}{
+		"all placeholders kept": {
+			original:    "Hello @maria, see ~general :wave:",
+			translation: "Hola @maria, mira ~general :wave:",
+			expected:    []string{},
+		},
+		"mention lost": {
+			original:    "Hello @maria",
+			translation: "Hola maria",
+			expected:    []string{`"@maria" is missing from the translation`},
+		},
+		"emoji and link altered": {
+			original:    "Done :white_check_mark: https://example.com/a",
+			translation: "Hecho :marca_de_verificacion: https://example.com/b",
+			expected: []string{
+				`":white_check_mark:" is missing from the translation`,
+				`"https://example.com/a" is missing from the translation`,
+			},
+		},
+		"one of repeated placeholders lost": {
+			original:    "@bob and @bob",
+			translation: "@bob y bob",
+			expected:    []string{`"@bob" is missing from the translation`},
+		},
+		"code translated": {
+			original:    "Use `git pull`",
+			translation: "Usa `git tirar`",
+			expected:    []string{"\"`git pull`\" is missing from the translation"},
+		},
+		"placeholders added by the translation are fine": {
+			original:    "Thanks",
+			translation: "Gracias :smile:",
+			expected:    []string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			warnings := validatePlaceholders(tc.original, tc.translation)
+			if !reflect.DeepEqual(warnings, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, warnings)
+			}
+		})
+	}
+}
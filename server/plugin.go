@@ -5,13 +5,16 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/mattermost/mattermost-plugin-ai/public/bridgeclient"
 	"github.com/mattermost/mattermost-plugin-channel-translations/server/enterprise"
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 )
 
 const (
@@ -24,6 +27,14 @@ type Plugin struct {
 	configuration     *configuration
 	pluginAPI         *pluginapi.Client
 	licenseChecker    *enterprise.LicenseChecker
+	sweeperJob        *cluster.Job
+	signingKey        []byte
+	botID             string
+
+	// pushTranslations are the translations for push notifications in progress, keyed by post and
+	// language.
+	pushTranslationsLock sync.Mutex
+	pushTranslations     map[string]*pushTranslation
 }
 
 func (p *Plugin) getTranslationEnabledKey(channelID string) string {
@@ -38,6 +49,20 @@ func (p *Plugin) setChannelTranslationEnabled(channelID string, enabled bool) er
 	return nil
 }
 
+// toggleChannelTranslations turns the translations of the channel on or off on behalf of the user.
+// The header and purpose were not translated while the channel wasn't, so they are translated
+// when it is turned on.
+func (p *Plugin) toggleChannelTranslations(channelID, userID string, enabled bool) error {
+	if err := p.setChannelTranslationEnabled(channelID, enabled); err != nil {
+		return err
+	}
+
+	if enabled && p.getConfiguration().EnableTranslations {
+		go p.translateChannelInfo(channelID, userID, userID)
+	}
+	return nil
+}
+
 func (p *Plugin) isChannelTranslationEnabled(channelID string) (bool, error) {
 	key := p.getTranslationEnabledKey(channelID)
 	var enabled bool
@@ -51,48 +76,128 @@ func (p *Plugin) isChannelTranslationEnabled(channelID string) (bool, error) {
 	return enabled, nil
 }
 
+// languageNames is the registry of the languages the plugin can translate to, keyed by code.
+var languageNames = map[string]string{
+	"bg":    "Bulgarian",
+	"de":    "German",
+	"en":    "English",
+	"en-AU": "English (Australia)",
+	"es":    "Spanish",
+	"fa":    "Persian",
+	"fr":    "French",
+	"hu":    "Hungarian",
+	"it":    "Italian",
+	"ja":    "Japanese",
+	"ko":    "Korean",
+	"nl":    "Dutch",
+	"pl":    "Polish",
+	"pt-BR": "Portuguese (Brazil)",
+	"ro":    "Romanian",
+	"ru":    "Russian",
+	"sv":    "Swedish",
+	"tr":    "Turkish",
+	"uk":    "Ukrainian",
+	"vi":    "Vietnamese",
+	"zh-CN": "Chinese (Simplified)",
+	"zh-TW": "Chinese (Traditional)",
+}
+
+// isSupportedLanguage checks if the language is in the registry of known languages.
+func isSupportedLanguage(langCode string) bool {
+	_, ok := languageNames[langCode]
+	return ok
+}
+
 func (p *Plugin) getLanguageName(langCode string) string {
-	languageMap := map[string]string{
-		"bg":    "Bulgarian",
-		"de":    "German",
-		"en":    "English",
-		"en-AU": "English (Australia)",
-		"es":    "Spanish",
-		"fa":    "Persian",
-		"fr":    "French",
-		"hu":    "Hungarian",
-		"it":    "Italian",
-		"ja":    "Japanese",
-		"ko":    "Korean",
-		"nl":    "Dutch",
-		"pl":    "Polish",
-		"pt-BR": "Portuguese (Brazil)",
-		"ro":    "Romanian",
-		"ru":    "Russian",
-		"sv":    "Swedish",
-		"tr":    "Turkish",
-		"uk":    "Ukrainian",
-		"vi":    "Vietnamese",
-		"zh-CN": "Chinese (Simplified)",
-		"zh-TW": "Chinese (Traditional)",
-	}
-
-	if name, exists := languageMap[langCode]; exists {
+	if name, exists := languageNames[langCode]; exists {
 		return name
 	}
 	return langCode
 }
 
+// getTranslationLanguages returns the configured languages that messages are translated to.
+func (p *Plugin) getTranslationLanguages() []string {
+	configured := p.getConfiguration().TranslationLanguages
+	if configured == "" {
+		return []string{"en"}
+	}
+
+	languages := []string{}
+	for _, lang := range strings.Split(configured, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang != "" {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// getChannelTranslationLanguages returns the languages the messages of the channel may be
+// translated to. There are no per-channel languages yet, so every channel allows the configured
+// languages and the channel is only taken so callers don't change once there are.
+func (p *Plugin) getChannelTranslationLanguages(channelID string) []string {
+	return p.getTranslationLanguages()
+}
+
+// isSelectableLanguage checks if users may pick the language as the one they read translations in.
+func (p *Plugin) isSelectableLanguage(langCode string) bool {
+	return isSupportedLanguage(langCode) && slices.Contains(p.getTranslationLanguages(), langCode)
+}
+
+// isAllowedLanguage checks if users may ask for translations of the channel messages into the
+// language. Only known languages are allowed, as the language name ends up in the prompt.
+func (p *Plugin) isAllowedLanguage(channelID, langCode string) bool {
+	return isSupportedLanguage(langCode) && slices.Contains(p.getChannelTranslationLanguages(channelID), langCode)
+}
+
 func (p *Plugin) OnActivate() error {
 	p.pluginAPI = pluginapi.NewClient(p.API, p.Driver)
 	p.licenseChecker = enterprise.NewLicenseChecker(p.pluginAPI)
 	if !p.licenseChecker.IsLicensed() {
 		return fmt.Errorf("invalid license, this software requires Mattermost Enterprise")
 	}
+
+	if err := p.ensureSigningKey(); err != nil {
+		return err
+	}
+
+	botID, err := p.pluginAPI.Bot.EnsureBot(&model.Bot{
+		Username:    "channel-translations",
+		DisplayName: "Channel Translations",
+		Description: "Sends notices about channel translations.",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure bot: %w", err)
+	}
+	p.botID = botID
+
+	if err := p.pluginAPI.SlashCommand.Register(p.getCommand()); err != nil {
+		return fmt.Errorf("failed to register command: %w", err)
+	}
+
+	job, err := cluster.Schedule(p.API, sweeperJobKey, cluster.MakeWaitForInterval(sweeperInterval), p.sweepStuckTranslations)
+	if err != nil {
+		return fmt.Errorf("failed to schedule stuck translations sweeper: %w", err)
+	}
+	p.sweeperJob = job
+
+	go p.runPostsMigration()
+
+	return nil
+}
+
+func (p *Plugin) OnDeactivate() error {
+	if p.sweeperJob != nil {
+		if err := p.sweeperJob.Close(); err != nil {
+			p.pluginAPI.Log.Warn("Failed to close stuck translations sweeper", "error", err.Error())
+		}
+	}
 	return nil
 }
 
-func (p *Plugin) translateText(message, requestorID, langCode string) (string, error) {
+// translateText translates the message into the language. The request is charged to the channel
+// the message belongs to.
+func (p *Plugin) translateText(message, requestorID, channelID, langCode string) (string, error) {
 	client := bridgeclient.NewClient(p.API)
 
 	// Get the bot user by username to obtain the bot ID
@@ -102,10 +207,11 @@ func (p *Plugin) translateText(message, requestorID, langCode string) (string, e
 		return "", fmt.Errorf("failed to get bot user: %w", err)
 	}
 
-	// Format the prompts with the parameters
+	// Format the prompts with the parameters. The message can't close the tag it is wrapped in, so
+	// whatever it says is translated rather than followed.
 	languageName := p.getLanguageName(langCode)
 	systemPrompt := strings.ReplaceAll(translationSystemPrompt, "{{.Parameters.Language}}", languageName)
-	userPrompt := strings.ReplaceAll(translationUserPrompt, "{{.Parameters.Message}}", message)
+	userPrompt := strings.ReplaceAll(translationUserPrompt, "{{.Parameters.Message}}", escapePromptTags(message))
 
 	// Build the completion request with posts
 	request := bridgeclient.CompletionRequest{
@@ -121,6 +227,13 @@ func (p *Plugin) translateText(message, requestorID, langCode string) (string, e
 		return "", completionErr
 	}
 
+	p.recordTranslationUsage(channelID, langCode, TranslationUsage{
+		Requests:     1,
+		InputTokens:  estimateTokens(systemPrompt) + estimateTokens(userPrompt),
+		OutputTokens: estimateTokens(translation),
+	})
+
+	translation = unescapePromptTags(translation)
 	if translation == "" {
 		translation = " "
 	}
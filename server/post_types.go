@@ -0,0 +1,175 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// postTypeFilterAllowlist translates only the listed post types.
+	postTypeFilterAllowlist = "allowlist"
+	// postTypeFilterDenylist translates every post type but the listed ones.
+	postTypeFilterDenylist = "denylist"
+)
+
+// PropText is a text found in the props of a post at a configured path.
+type PropText struct {
+	// Path is the path of the text in the props, with the position of every element it went through.
+	Path string
+	Text string
+}
+
+// getPostTypes returns the post types of the allowlist or denylist.
+func (c *configuration) getPostTypes() []string {
+	return splitList(c.PostTypes)
+}
+
+// getPropPaths returns the configured paths of the props to translate, keyed by post type. Each
+// line of the setting holds a post type and its comma separated paths, as in
+// "custom_poll: question, options.*.text".
+func (c *configuration) getPropPaths() map[string][]string {
+	paths := make(map[string][]string)
+	for _, line := range strings.Split(c.PropPaths, "\n") {
+		postType, list, ok := strings.Cut(line, ":")
+		postType = strings.TrimSpace(postType)
+		if !ok || postType == "" {
+			continue
+		}
+		paths[postType] = append(paths[postType], splitList(list)...)
+	}
+	return paths
+}
+
+// splitList splits a comma separated setting, dropping empty items.
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isTranslatedPostType checks if posts of the type are translated. Regular posts always are, and
+// system messages of the server never are, as the server rejects updates of them and their
+// translations could never be stored. Other types follow the configured allowlist or denylist, and
+// without one they are translated along with system messages or when they have props to translate.
+func (p *Plugin) isTranslatedPostType(postType string) bool {
+	if postType == "" || postType == model.PostTypeSlackAttachment || postType == legacyTranslationPostType {
+		return true
+	}
+	if strings.HasPrefix(postType, model.PostSystemMessagePrefix) {
+		return false
+	}
+
+	config := p.getConfiguration()
+	_, hasPropPaths := config.getPropPaths()[postType]
+	switch config.PostTypeFilter {
+	case postTypeFilterAllowlist:
+		return slices.Contains(config.getPostTypes(), postType)
+	case postTypeFilterDenylist:
+		return !slices.Contains(config.getPostTypes(), postType)
+	default:
+		return config.TranslateSystemMessages || hasPropPaths
+	}
+}
+
+// getPropTexts returns the texts found in the props of the post at the paths configured for its
+// type, in a stable order.
+func (p *Plugin) getPropTexts(post *model.Post) []PropText {
+	paths := p.getConfiguration().getPropPaths()[post.Type]
+	if len(paths) == 0 {
+		return nil
+	}
+
+	texts := []PropText{}
+	for _, path := range paths {
+		collectPropTexts(post.GetProps(), strings.Split(path, "."), "", &texts)
+	}
+	return texts
+}
+
+// collectPropTexts walks the value along the path, where "*" goes through every element of an
+// array or object, and adds the non-empty strings it ends at.
+func collectPropTexts(value interface{}, path []string, resolved string, texts *[]PropText) {
+	if len(path) == 0 {
+		if text, ok := value.(string); ok && strings.TrimSpace(text) != "" {
+			*texts = append(*texts, PropText{Path: resolved, Text: text})
+		}
+		return
+	}
+
+	join := func(key string) string {
+		if resolved == "" {
+			return key
+		}
+		return resolved + "." + key
+	}
+
+	segment, rest := path[0], path[1:]
+	switch node := value.(type) {
+	case map[string]interface{}:
+		if segment != "*" {
+			collectPropTexts(node[segment], rest, join(segment), texts)
+			return
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectPropTexts(node[key], rest, join(key), texts)
+		}
+	case model.StringInterface:
+		collectPropTexts(map[string]interface{}(node), path, resolved, texts)
+	case []interface{}:
+		if segment == "*" {
+			for i, item := range node {
+				collectPropTexts(item, rest, join(strconv.Itoa(i)), texts)
+			}
+			return
+		}
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(node) {
+			collectPropTexts(node[i], rest, join(segment), texts)
+		}
+	}
+}
+
+// translateProps translates the texts in the props of the post at the paths configured for its
+// type, keyed by path.
+func (p *Plugin) translateProps(post *model.Post, requestorID, langCode string) (map[string]string, error) {
+	texts := p.getPropTexts(post)
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	translations := make(map[string]string)
+	for _, text := range texts {
+		translation, err := p.translateWithRetry(text.Text, requestorID, post.ChannelId, langCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate prop %s: %w", text.Path, err)
+		}
+		translations[text.Path] = translation
+	}
+	return translations, nil
+}
+
+// isSamePropText checks if the translated props read the same as the originals.
+func (p *Plugin) isSamePropText(post *model.Post, translations map[string]string) bool {
+	for _, text := range p.getPropTexts(post) {
+		if strings.TrimSpace(translations[text.Path]) != strings.TrimSpace(text.Text) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+func TestGetPropPaths(t *testing.T) {
+	for name, tc := range map[string]struct {
+		propPaths string
+		expected  map[string][]string
+	}{
+		"empty setting": {
+			propPaths: "",
+			expected:  map[string][]string{},
+		},
+		"several post types": {
+			propPaths: "custom_poll: question, options.*.text\ncustom_card:title",
+			expected: map[string][]string{
+				"custom_poll": {"question", "options.*.text"},
+				"custom_card": {"title"},
+			},
+		},
+		"post type on several lines": {
+			propPaths: "custom_poll: question\ncustom_poll: options.*.text",
+			expected:  map[string][]string{"custom_poll": {"question", "options.*.text"}},
+		},
+		"blank and malformed lines are skipped": {
+			propPaths: "\n  \nno colon here\n: title\ncustom_card: , title ,",
+			expected:  map[string][]string{"custom_card": {"title"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{Config{PropPaths: tc.propPaths}}
+			if paths := config.getPropPaths(); !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+}
+
+func TestCollectPropTexts(t *testing.T) {
+	props := map[string]interface{}{
+		"question": "Where do we meet?",
+		"options": []interface{}{
+			map[string]interface{}{"text": "Office", "votes": float64(3)},
+			map[string]interface{}{"text": "  "},
+			map[string]interface{}{"text": "Online"},
+		},
+		"labels": map[string]interface{}{
+			"yes": "Yes",
+			"no":  "No",
+		},
+		"card":  model.StringInterface{"title": "Weekly sync"},
+		"count": float64(2),
+	}
+
+	for name, tc := range map[string]struct {
+		path     string
+		expected []PropText
+	}{
+		"plain key": {
+			path:     "question",
+			expected: []PropText{{Path: "question", Text: "Where do we meet?"}},
+		},
+		"wildcard over an array": {
+			path: "options.*.text",
+			expected: []PropText{
+				{Path: "options.0.text", Text: "Office"},
+				{Path: "options.2.text", Text: "Online"},
+			},
+		},
+		"array index": {
+			path:     "options.2.text",
+			expected: []PropText{{Path: "options.2.text", Text: "Online"}},
+		},
+		"array index out of range": {
+			path:     "options.3.text",
+			expected: []PropText{},
+		},
+		"negative array index": {
+			path:     "options.-1.text",
+			expected: []PropText{},
+		},
+		"wildcard over an object in key order": {
+			path: "labels.*",
+			expected: []PropText{
+				{Path: "labels.no", Text: "No"},
+				{Path: "labels.yes", Text: "Yes"},
+			},
+		},
+		"string interface": {
+			path:     "card.title",
+			expected: []PropText{{Path: "card.title", Text: "Weekly sync"}},
+		},
+		"value that is not a string": {
+			path:     "count",
+			expected: []PropText{},
+		},
+		"path ending before a string": {
+			path:     "options.*",
+			expected: []PropText{},
+		},
+		"missing key": {
+			path:     "missing.text",
+			expected: []PropText{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			texts := []PropText{}
+			collectPropTexts(props, strings.Split(tc.path, "."), "", &texts)
+			if !reflect.DeepEqual(texts, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, texts)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+// postUpdateLockKeyPrefix is the prefix of the cluster locks the plugin's writes of a post are
+// serialized with.
+const postUpdateLockKeyPrefix = "post_update"
+
+// errStaleTranslation is returned when the post changed after its translation was started.
+var errStaleTranslation = errors.New("post text changed while it was being translated")
+
+// staleTranslationRejection is the reason MessageWillBeUpdated rejects a write of the plugin that
+// would undo an edit made since the post was read.
+const staleTranslationRejection = "stale translation"
+
+// updatePostTranslations re-reads the post and applies the given changes to the latest version
+// of it before writing it back, so concurrent edits are never overwritten. The changes are only
+// written if the post still has the message the translations were produced for, otherwise
+// errStaleTranslation is returned and the translations are discarded. Edits made between reading
+// and writing the post are caught by MessageWillBeUpdated, which rejects the write. Nothing is
+// written if apply returns an error.
+func (p *Plugin) updatePostTranslations(post *model.Post, apply func(current *model.Post) error) error {
+	// Serialize the plugin's own writes across the cluster so parallel translations, hooks and the
+	// sweeper don't overwrite each other's statuses
+	mutex, err := cluster.NewMutex(p.API, fmt.Sprintf("%s_%s", postUpdateLockKeyPrefix, post.Id))
+	if err != nil {
+		return fmt.Errorf("failed to create post update lock: %w", err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	current, err := p.pluginAPI.Post.GetPost(post.Id)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+
+	if current.DeleteAt != 0 || !p.hasSameContent(current, post) {
+		return errStaleTranslation
+	}
+
+	if err := apply(current); err != nil {
+		return err
+	}
+	current.AddProp(translationRevisionProp, getTranslationRevision(current)+1)
+	p.signTranslationProps(current)
+
+	if err := p.pluginAPI.Post.UpdatePost(current); err != nil {
+		// The write is rejected when the post was edited in the meantime
+		if latest, getErr := p.pluginAPI.Post.GetPost(post.Id); getErr == nil && !p.hasSameContent(latest, post) {
+			return errStaleTranslation
+		}
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	return nil
+}
+
+// storePostTranslation saves a translation of the post into a single language for everyone, as
+// requested on demand by a user allowed to manage the channel.
+func (p *Plugin) storePostTranslation(post *model.Post, langCode string, content TranslatedContent, userID string) error {
+	provenance := p.newTranslationProvenance(userID)
+	return p.updatePostTranslations(post, func(current *model.Post) error {
+		if err := p.saveTranslations(current, map[string]TranslatedContent{langCode: content}, provenance); err != nil {
+			return err
+		}
+
+		statuses := getPostTranslationStatuses(current)
+		statuses[langCode] = LanguageStatus{Status: TranslationStatusDone}
+		current.AddProp(translationStatusProp, statuses)
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+const (
+	// translationBackend is the service the plugin uses to translate messages.
+	translationBackend = "mattermost-agents"
+
+	// translationGlossaryVersion identifies the glossary applied to translations. No glossary is
+	// applied yet.
+	translationGlossaryVersion = "none"
+
+	// requestedByAuto is recorded for translations made automatically, without a user asking.
+	requestedByAuto = "auto"
+)
+
+// TranslationProvenance records how a translation was made.
+type TranslationProvenance struct {
+	Backend         string `json:"backend"`
+	BotID           string `json:"botId"`
+	BotUsername     string `json:"botUsername"`
+	PromptVersion   int    `json:"promptVersion"`
+	GlossaryVersion string `json:"glossaryVersion"`
+	// RequestedBy is the ID of the user who asked for the translation, or requestedByAuto.
+	RequestedBy string `json:"requestedBy"`
+}
+
+// newTranslationProvenance returns the provenance of translations made now with the configured
+// bot for the given requester.
+func (p *Plugin) newTranslationProvenance(requestedBy string) *TranslationProvenance {
+	provenance := &TranslationProvenance{
+		Backend:         translationBackend,
+		BotUsername:     p.getConfiguration().TranslationBotName,
+		PromptVersion:   translationPromptVersion,
+		GlossaryVersion: translationGlossaryVersion,
+		RequestedBy:     requestedBy,
+	}
+
+	if botUser, err := p.pluginAPI.User.GetByUsername(provenance.BotUsername); err == nil {
+		provenance.BotID = botUser.Id
+	}
+	return provenance
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// pushTranslationTimeout is how long a push notification waits for its message to be translated.
+	pushTranslationTimeout = 2 * time.Second
+	// maxPushTranslationLength is the length of the longest message translated for a push
+	// notification when there is no translation of it yet.
+	maxPushTranslationLength = 500
+	// pushTranslationPollInterval is how often a push notification checks for the translation of its
+	// message while the post is being translated.
+	pushTranslationPollInterval = 200 * time.Millisecond
+)
+
+// NotificationWillBePushed shows the message of the post in the language of the recipient. Stored
+// translations are used when there are some, and short messages are translated on the fly. The
+// notification goes out untranslated if that takes too long.
+func (p *Plugin) NotificationWillBePushed(pushNotification *model.PushNotification, userID string) (*model.PushNotification, string) {
+	// Notifications without the message are filled in by the device, and generic ones have none
+	if !p.getConfiguration().EnableTranslations || pushNotification.IsIdLoaded || pushNotification.PostId == "" || pushNotification.Message == "" {
+		return nil, ""
+	}
+
+	lang, err := p.resolveUserTranslationLanguage(userID)
+	if err != nil || lang == "" || !p.isAllowedLanguage(pushNotification.ChannelId, lang) {
+		return nil, ""
+	}
+
+	post, err := p.pluginAPI.Post.GetPost(pushNotification.PostId)
+	if err != nil || post.Message == "" || !p.isTranslatedPostType(post.Type) {
+		return nil, ""
+	}
+
+	// The notification holds the message after the name of the sender, unless it was cut short
+	if !strings.Contains(pushNotification.Message, post.Message) {
+		return nil, ""
+	}
+
+	translation := p.getPushTranslation(post, userID, lang)
+	if translation == "" {
+		return nil, ""
+	}
+
+	translated := pushNotification.DeepCopy()
+	translated.Message = strings.Replace(pushNotification.Message, post.Message, translation, 1)
+	return translated, ""
+}
+
+// pushTranslation is a translation of a message made for push notifications. The notifications of
+// every recipient reading the language wait for the same one.
+type pushTranslation struct {
+	done chan struct{}
+	text string
+	// userID is the recipient the translation is charged to.
+	userID string
+}
+
+// getPushTranslation returns the translation of the message of the post for a push notification,
+// or an empty string if there is none in time. Languages the post is being translated into
+// automatically are waited for, and the others are translated on the fly.
+func (p *Plugin) getPushTranslation(post *model.Post, userID, langCode string) string {
+	if stored, err := p.getStoredTranslations([]*model.Post{post}, langCode); err == nil && stored[post.Id] != nil {
+		return stored[post.Id].Text
+	}
+	if personal, err := p.getPersonalTranslations(userID, []*model.Post{post}, langCode); err == nil && personal[post.Id] != nil {
+		return personal[post.Id].Text
+	}
+
+	if getPostTranslationStatuses(post)[langCode].Status == TranslationStatusPending && slices.Contains(p.getChannelTranslationLanguages(post.ChannelId), langCode) {
+		return p.waitForStoredTranslation(post, langCode)
+	}
+
+	// Only channels with translations turned on are translated on the fly
+	if len(post.Message) > maxPushTranslationLength {
+		return ""
+	}
+	if !p.isChannelAutoTranslated(post.ChannelId) {
+		return ""
+	}
+
+	translation := p.startPushTranslation(post, userID, langCode)
+	select {
+	case <-translation.done:
+		// Recipients who waited for the translation of another one keep it as well
+		if translation.text != "" && translation.userID != userID {
+			if err := p.savePersonalTranslation(userID, post, langCode, TranslatedContent{Text: translation.text}, p.newTranslationProvenance(translation.userID)); err != nil {
+				p.pluginAPI.Log.Warn("Failed to save push notification translation", "post_id", post.Id, "language", langCode, "error", err.Error())
+			}
+		}
+		return strings.TrimSpace(translation.text)
+	case <-time.After(pushTranslationTimeout):
+		p.pluginAPI.Log.Debug("Push notification sent before its translation was ready", "post_id", post.Id, "language", langCode)
+		return ""
+	}
+}
+
+// waitForStoredTranslation waits for the translation of the post into the language to be stored,
+// for as long as a push notification can wait.
+func (p *Plugin) waitForStoredTranslation(post *model.Post, langCode string) string {
+	for deadline := time.Now().Add(pushTranslationTimeout); time.Now().Before(deadline); {
+		time.Sleep(pushTranslationPollInterval)
+		if stored, err := p.getStoredTranslations([]*model.Post{post}, langCode); err == nil && stored[post.Id] != nil {
+			return strings.TrimSpace(stored[post.Id].Text)
+		}
+	}
+
+	p.pluginAPI.Log.Debug("Push notification sent before its translation was ready", "post_id", post.Id, "language", langCode)
+	return ""
+}
+
+// startPushTranslation returns the translation of the message of the post into the language for
+// push notifications, starting it unless it is already in progress. It is charged to the recipient
+// who starts it, within the limits of on-demand translations, and kept as a personal translation
+// of the recipient, even when it comes too late for the notification.
+func (p *Plugin) startPushTranslation(post *model.Post, userID, langCode string) *pushTranslation {
+	key := post.Id + "_" + langCode
+
+	p.pushTranslationsLock.Lock()
+	defer p.pushTranslationsLock.Unlock()
+
+	if translation, ok := p.pushTranslations[key]; ok {
+		return translation
+	}
+	translation := &pushTranslation{done: make(chan struct{}), userID: userID}
+	if p.pushTranslations == nil {
+		p.pushTranslations = make(map[string]*pushTranslation)
+	}
+	p.pushTranslations[key] = translation
+
+	go func() {
+		defer close(translation.done)
+		defer func() {
+			p.pushTranslationsLock.Lock()
+			delete(p.pushTranslations, key)
+			p.pushTranslationsLock.Unlock()
+		}()
+
+		if err := p.checkOnDemandLimits(userID, post.ChannelId, 1); err != nil {
+			p.pluginAPI.Log.Debug("Push notification not translated", "post_id", post.Id, "language", langCode, "error", err.Error())
+			return
+		}
+
+		text, err := p.translateText(post.Message, userID, post.ChannelId, langCode)
+		if err != nil {
+			p.pluginAPI.Log.Warn("Failed to translate push notification", "post_id", post.Id, "language", langCode, "error", err.Error())
+			return
+		}
+		translation.text = text
+
+		if err := p.savePersonalTranslation(userID, post, langCode, TranslatedContent{Text: text}, p.newTranslationProvenance(userID)); err != nil {
+			p.pluginAPI.Log.Warn("Failed to save push notification translation", "post_id", post.Id, "language", langCode, "error", err.Error())
+		}
+	}()
+
+	return translation
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const (
+	rateLimitKeyPrefix = "ratelimit"
+	usageKeyPrefix     = "usage"
+
+	// usageCounterTTL keeps the daily counters around a bit longer than the day they count.
+	usageCounterTTL = 48 * time.Hour
+
+	// rateLimitTTL drops idle buckets once they would have refilled anyway.
+	rateLimitTTL = 10 * time.Minute
+
+	setAtomicRetries = 5
+)
+
+// errRateLimited is returned when a user or team has used up its on-demand translations for now.
+type errRateLimited struct {
+	reason     string
+	retryAfter time.Duration
+}
+
+func (e *errRateLimited) Error() string {
+	return e.reason
+}
+
+// errRequestTooLarge is returned when a request costs more translations than a limit allows at
+// once, so trying it again later would not help.
+type errRequestTooLarge struct {
+	reason string
+}
+
+func (e *errRequestTooLarge) Error() string {
+	return e.reason
+}
+
+// tokenBucket is the state of a rate limit, stored in the KV store.
+type tokenBucket struct {
+	Tokens   float64 `json:"tokens"`
+	UpdateAt int64   `json:"updateAt"`
+}
+
+func getRateLimitKey(scope, id string) string {
+	return fmt.Sprintf("%s_%s_%s", rateLimitKeyPrefix, scope, id)
+}
+
+func getUsageKey(scope, id, day string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", usageKeyPrefix, scope, id, day)
+}
+
+// setAtomicWithExpiry works like KV.SetAtomicWithRetries, but the key expires after ttl so
+// counters don't pile up in the KV store.
+func (p *Plugin) setAtomicWithExpiry(key string, ttl time.Duration, valueFunc func(oldValue []byte) (interface{}, error)) error {
+	for range setAtomicRetries {
+		var oldValue []byte
+		if err := p.pluginAPI.KV.Get(key, &oldValue); err != nil {
+			return err
+		}
+
+		newValue, err := valueFunc(oldValue)
+		if err != nil {
+			return err
+		}
+
+		saved, err := p.pluginAPI.KV.Set(key, newValue, pluginapi.SetAtomic(oldValue), pluginapi.SetExpiry(ttl))
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to set %s after %d retries", key, setAtomicRetries)
+}
+
+// takeTokens removes cost tokens from the bucket, which holds up to perMinute tokens and refills
+// at perMinute tokens a minute. A limit of zero or less means no limit.
+func (p *Plugin) takeTokens(scope, id string, perMinute int, cost int) error {
+	if perMinute <= 0 || id == "" {
+		return nil
+	}
+
+	capacity := float64(perMinute)
+	refillPerMilli := capacity / float64(time.Minute.Milliseconds())
+	var limited *errRateLimited
+	err := p.setAtomicWithExpiry(getRateLimitKey(scope, id), rateLimitTTL, func(oldValue []byte) (interface{}, error) {
+		limited = nil
+		now := model.GetMillis()
+		bucket := tokenBucket{Tokens: capacity, UpdateAt: now}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &bucket); err != nil {
+				return nil, err
+			}
+			bucket.Tokens = math.Min(capacity, bucket.Tokens+float64(now-bucket.UpdateAt)*refillPerMilli)
+			bucket.UpdateAt = now
+		}
+
+		if bucket.Tokens < float64(cost) {
+			wait := time.Duration(math.Ceil((float64(cost)-bucket.Tokens)/refillPerMilli)) * time.Millisecond
+			limited = &errRateLimited{reason: fmt.Sprintf("too many translations requested by %s", scope), retryAfter: wait}
+			return bucket, nil
+		}
+
+		bucket.Tokens -= float64(cost)
+		return bucket, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+	if limited != nil {
+		return limited
+	}
+	return nil
+}
+
+// countDailyUsage adds cost to the translations made today, unless it would go over the quota. A
+// quota of zero or less means no quota.
+func (p *Plugin) countDailyUsage(scope, id string, quota int, cost int) error {
+	if quota <= 0 || id == "" {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	var limited *errRateLimited
+	err := p.setAtomicWithExpiry(getUsageKey(scope, id, now.Format(time.DateOnly)), usageCounterTTL, func(oldValue []byte) (interface{}, error) {
+		limited = nil
+		used := 0
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &used); err != nil {
+				return nil, err
+			}
+		}
+
+		if used+cost > quota {
+			tomorrow := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+			limited = &errRateLimited{reason: fmt.Sprintf("daily translation quota of %s reached", scope), retryAfter: tomorrow.Sub(now)}
+			return used, nil
+		}
+		return used + cost, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update translation usage: %w", err)
+	}
+	if limited != nil {
+		return limited
+	}
+	return nil
+}
+
+// checkRequestCost returns an *errRequestTooLarge when the cost of a request is over one of the
+// configured limits, as a bucket never holds more than its limit. Team limits only apply to
+// channels of a team.
+func checkRequestCost(config *configuration, hasTeam bool, cost int) error {
+	limits := []int{config.UserTranslationsPerMinute, config.UserDailyTranslationQuota}
+	if hasTeam {
+		limits = append(limits, config.TeamTranslationsPerMinute, config.TeamDailyTranslationQuota)
+	}
+	for _, limit := range limits {
+		if limit > 0 && cost > limit {
+			return &errRequestTooLarge{reason: fmt.Sprintf("the request needs %d translations, more than the limits allow at once (%d)", cost, limit)}
+		}
+	}
+	return nil
+}
+
+// checkOnDemandLimits charges cost on-demand translations to the user and the team of the channel,
+// returning an *errRateLimited when any of the configured limits is reached. Limits checked before
+// the one reached still count the request.
+func (p *Plugin) checkOnDemandLimits(userID, channelID string, cost int) error {
+	config := p.getConfiguration()
+
+	teamID := ""
+	if channel, err := p.pluginAPI.Channel.Get(channelID); err == nil {
+		teamID = channel.TeamId
+	}
+
+	if err := checkRequestCost(config, teamID != "", cost); err != nil {
+		return err
+	}
+
+	if err := p.takeTokens("user", userID, config.UserTranslationsPerMinute, cost); err != nil {
+		return err
+	}
+	if err := p.takeTokens("team", teamID, config.TeamTranslationsPerMinute, cost); err != nil {
+		return err
+	}
+	if err := p.countDailyUsage("user", userID, config.UserDailyTranslationQuota, cost); err != nil {
+		return err
+	}
+	return p.countDailyUsage("team", teamID, config.TeamDailyTranslationQuota, cost)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckRequestCost(t *testing.T) {
+	config := &configuration{Config{
+		UserTranslationsPerMinute: 10,
+		UserDailyTranslationQuota: 100,
+		TeamTranslationsPerMinute: 5,
+	}}
+
+	for name, tc := range map[string]struct {
+		config   *configuration
+		hasTeam  bool
+		cost     int
+		expected bool
+	}{
+		"no limits":                        {config: &configuration{}, hasTeam: true, cost: 1000, expected: false},
+		"within every limit":               {config: config, hasTeam: true, cost: 5, expected: false},
+		"over the limit of the team":       {config: config, hasTeam: true, cost: 6, expected: true},
+		"team limit outside of a team":     {config: config, hasTeam: false, cost: 6, expected: false},
+		"over the limit of the user":       {config: config, hasTeam: false, cost: 11, expected: true},
+		"over the daily quota of the user": {config: &configuration{Config{UserDailyTranslationQuota: 3}}, cost: 4, expected: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkRequestCost(tc.config, tc.hasTeam, tc.cost)
+			if tooLarge := err != nil; tooLarge != tc.expected {
+				t.Errorf("expected too large: %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
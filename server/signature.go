@@ -0,0 +1,240 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const (
+	signingKeyKey            = "translation_signing_key"
+	translationSignatureProp = "translation_signature"
+	// originalMessageSignatureProp marks the original message of a post as written by the plugin.
+	originalMessageSignatureProp = "original_message_signature"
+)
+
+// translationProps are the post props only the plugin is allowed to write.
+var translationProps = []string{
+	translationsProp,
+	translationStatusProp,
+	translationRevisionProp,
+	translationSignatureProp,
+}
+
+// ensureSigningKey loads the key used to sign the translation props, creating it the first time
+// the plugin runs. Every server of the cluster ends up with the same key.
+func (p *Plugin) ensureSigningKey() error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	if _, err := p.pluginAPI.KV.Set(signingKeyKey, secret, pluginapi.SetAtomic(nil)); err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+
+	var key []byte
+	if err := p.pluginAPI.KV.Get(signingKeyKey, &key); err != nil {
+		return fmt.Errorf("failed to get signing key: %w", err)
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("signing key is empty")
+	}
+
+	p.signingKey = key
+	return nil
+}
+
+// computeTranslationSignature returns the signature of the translation state of the post.
+func (p *Plugin) computeTranslationSignature(post *model.Post) string {
+	statuses, _ := json.Marshal(getPostTranslationStatuses(post))
+
+	mac := hmac.New(sha256.New, p.signingKey)
+	mac.Write([]byte(post.Id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(getTranslationRevision(post), 10)))
+	mac.Write([]byte{0})
+	mac.Write(statuses)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signTranslationProps marks the translation state of the post as written by the plugin.
+func (p *Plugin) signTranslationProps(post *model.Post) {
+	post.AddProp(translationSignatureProp, p.computeTranslationSignature(post))
+}
+
+// hasValidTranslationSignature checks if the translation state of the post was written by the
+// plugin.
+func (p *Plugin) hasValidTranslationSignature(post *model.Post) bool {
+	signature, ok := post.GetProp(translationSignatureProp).(string)
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(p.computeTranslationSignature(post)))
+}
+
+// computeOriginalMessageSignature returns the signature tying the original message to the author,
+// channel and message of the post. New posts have no id yet, so it isn't part of it.
+func (p *Plugin) computeOriginalMessageSignature(post *model.Post, original string) string {
+	mac := hmac.New(sha256.New, p.signingKey)
+	for _, part := range []string{originalMessageProp, post.UserId, post.ChannelId, post.Message, original} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signOriginalMessage keeps the text the message of the post was translated from.
+func (p *Plugin) signOriginalMessage(post *model.Post, original string) {
+	post.AddProp(originalMessageProp, original)
+	post.AddProp(originalMessageSignatureProp, p.computeOriginalMessageSignature(post, original))
+}
+
+// stripUnsignedOriginalMessage returns the post without its original message, unless the plugin
+// wrote it for the current message.
+func (p *Plugin) stripUnsignedOriginalMessage(post *model.Post) *model.Post {
+	if post.GetProp(originalMessageProp) == nil && post.GetProp(originalMessageSignatureProp) == nil {
+		return post
+	}
+
+	original, _ := post.GetProp(originalMessageProp).(string)
+	signature, _ := post.GetProp(originalMessageSignatureProp).(string)
+	if original != "" && hmac.Equal([]byte(signature), []byte(p.computeOriginalMessageSignature(post, original))) {
+		return post
+	}
+
+	stripped := post.Clone()
+	stripped.DelProp(originalMessageProp)
+	stripped.DelProp(originalMessageSignatureProp)
+	return stripped
+}
+
+// protectOriginalMessage returns the updated post with the original message of the previous
+// version. The original no longer matches an edited message, so it is dropped then.
+func protectOriginalMessage(newPost, oldPost *model.Post) *model.Post {
+	protected := newPost
+	for _, prop := range []string{originalMessageProp, originalMessageSignatureProp} {
+		value := oldPost.GetProp(prop)
+		if newPost.Message != oldPost.Message {
+			value = nil
+		}
+		if reflect.DeepEqual(normalizeProp(newPost.GetProp(prop)), normalizeProp(value)) {
+			continue
+		}
+		if protected == newPost {
+			protected = newPost.Clone()
+		}
+		if value != nil {
+			protected.AddProp(prop, value)
+		} else {
+			protected.DelProp(prop)
+		}
+	}
+	return protected
+}
+
+// computeShareSignature returns the signature of a translation offered to the user to share in a
+// channel, so the share action only posts what the plugin translated for that user and place.
+func (p *Plugin) computeShareSignature(userID, channelID, rootID, original, translation string) string {
+	mac := hmac.New(sha256.New, p.signingKey)
+	for _, part := range []string{"share", userID, channelID, rootID, original, translation} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hasValidShareSignature checks if the translation to share was offered by the plugin to the user
+// in the channel and thread.
+func (p *Plugin) hasValidShareSignature(signature, userID, channelID, rootID, original, translation string) bool {
+	return hmac.Equal([]byte(signature), []byte(p.computeShareSignature(userID, channelID, rootID, original, translation)))
+}
+
+// stripTranslationProps returns the post without any translation props. Clients can't make up
+// translations, so whatever they send is dropped.
+func stripTranslationProps(post *model.Post) *model.Post {
+	stripped := post
+	for _, prop := range translationProps {
+		if post.GetProp(prop) == nil {
+			continue
+		}
+		if stripped == post {
+			stripped = post.Clone()
+		}
+		stripped.DelProp(prop)
+	}
+	return stripped
+}
+
+// hasSameTranslationProps checks if both posts carry the same translation props.
+func hasSameTranslationProps(post, other *model.Post) bool {
+	for _, prop := range translationProps {
+		if !reflect.DeepEqual(normalizeProp(post.GetProp(prop)), normalizeProp(other.GetProp(prop))) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeProp converts a prop to its decoded JSON form, so props written by the plugin compare
+// equal to the same props read back from the database.
+func normalizeProp(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// protectTranslationProps returns the updated post with the translation props of the previous
+// version, unless they were changed by the plugin itself. The signature doesn't cover the
+// translations that earlier versions of the plugin kept in the props, which the plugin only ever
+// removes, so they are given back on every other change.
+func (p *Plugin) protectTranslationProps(newPost, oldPost *model.Post) *model.Post {
+	if hasSameTranslationProps(newPost, oldPost) {
+		return newPost
+	}
+
+	restored := translationProps
+	if p.hasValidTranslationSignature(newPost) && getTranslationRevision(newPost) >= getTranslationRevision(oldPost) {
+		if isPluginUpdate(newPost, oldPost) && newPost.GetProp(translationsProp) == nil {
+			return newPost
+		}
+		restored = []string{translationsProp}
+	}
+
+	protected := newPost
+	for _, prop := range restored {
+		value := oldPost.GetProp(prop)
+		if reflect.DeepEqual(normalizeProp(newPost.GetProp(prop)), normalizeProp(value)) {
+			continue
+		}
+		if protected == newPost {
+			protected = newPost.Clone()
+		}
+		if value != nil {
+			protected.AddProp(prop, value)
+		} else {
+			protected.DelProp(prop)
+		}
+	}
+	return protected
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+func TestStripUnsignedOriginalMessage(t *testing.T) {
+	p := &Plugin{signingKey: []byte("signing key")}
+	newPost := func(message string) *model.Post {
+		return &model.Post{UserId: "user", ChannelId: "channel", Message: message}
+	}
+
+	for name, tc := range map[string]struct {
+		post     func() *model.Post
+		expected interface{}
+	}{
+		"no original message": {
+			post:     func() *model.Post { return newPost("hello") },
+			expected: nil,
+		},
+		"signed by the plugin": {
+			post: func() *model.Post {
+				post := newPost("hello")
+				p.signOriginalMessage(post, "hola")
+				return post
+			},
+			expected: "hola",
+		},
+		"set by the client": {
+			post: func() *model.Post {
+				post := newPost("hello")
+				post.AddProp(originalMessageProp, "hola")
+				return post
+			},
+			expected: nil,
+		},
+		"changed by the client": {
+			post: func() *model.Post {
+				post := newPost("hello")
+				p.signOriginalMessage(post, "hola")
+				post.AddProp(originalMessageProp, "adiós")
+				return post
+			},
+			expected: nil,
+		},
+		"signed for another message": {
+			post: func() *model.Post {
+				post := newPost("hello")
+				p.signOriginalMessage(post, "hola")
+				post.Message = "goodbye"
+				return post
+			},
+			expected: nil,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post := p.stripUnsignedOriginalMessage(tc.post())
+			if original := post.GetProp(originalMessageProp); original != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, original)
+			}
+			if tc.expected == nil && post.GetProp(originalMessageSignatureProp) != nil {
+				t.Errorf("expected the signature to be dropped")
+			}
+		})
+	}
+}
+
+func TestProtectOriginalMessage(t *testing.T) {
+	oldPost := &model.Post{Message: "hello"}
+	oldPost.AddProp(originalMessageProp, "hola")
+	oldPost.AddProp(originalMessageSignatureProp, "signature")
+
+	for name, tc := range map[string]struct {
+		message  string
+		original interface{}
+		expected interface{}
+	}{
+		"unchanged": {
+			message:  "hello",
+			original: "hola",
+			expected: "hola",
+		},
+		"changed by the client": {
+			message:  "hello",
+			original: "adiós",
+			expected: "hola",
+		},
+		"removed by the client": {
+			message:  "hello",
+			expected: "hola",
+		},
+		"message edited": {
+			message:  "hello again",
+			original: "hola",
+			expected: nil,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			newPost := &model.Post{Message: tc.message}
+			if tc.original != nil {
+				newPost.AddProp(originalMessageProp, tc.original)
+			}
+
+			post := protectOriginalMessage(newPost, oldPost)
+			if original := post.GetProp(originalMessageProp); original != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, original)
+			}
+			if (post.GetProp(originalMessageSignatureProp) != nil) != (tc.expected != nil) {
+				t.Errorf("expected the signature to follow the original message")
+			}
+		})
+	}
+}
+
+// newTranslatedPost returns a post with the given translation state, signed by the plugin when
+// asked to.
+func newTranslatedPost(p *Plugin, revision int64, statuses map[string]LanguageStatus, translations map[string]interface{}, signed bool) *model.Post {
+	post := &model.Post{Id: "post1", Message: "Hello"}
+	post.AddProp(translationRevisionProp, revision)
+	if statuses != nil {
+		post.AddProp(translationStatusProp, statuses)
+	}
+	if translations != nil {
+		post.AddProp(translationsProp, translations)
+	}
+	if signed {
+		p.signTranslationProps(post)
+	}
+	return post
+}
+
+func TestProtectTranslationProps(t *testing.T) {
+	p := &Plugin{signingKey: []byte("signing key")}
+	done := map[string]LanguageStatus{"es": {Status: TranslationStatusDone}}
+	pending := map[string]LanguageStatus{"es": {Status: TranslationStatusPending}}
+	legacy := map[string]interface{}{"es": "Hola"}
+	oldPost := newTranslatedPost(p, 2, done, legacy, true)
+
+	for name, tc := range map[string]struct {
+		newPost              *model.Post
+		expectedRevision     int64
+		expectedStatuses     map[string]LanguageStatus
+		expectedTranslations interface{}
+	}{
+		"unchanged": {
+			newPost:              newTranslatedPost(p, 2, done, legacy, true),
+			expectedRevision:     2,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"written by the plugin": {
+			newPost:              newTranslatedPost(p, 3, pending, legacy, true),
+			expectedRevision:     3,
+			expectedStatuses:     pending,
+			expectedTranslations: legacy,
+		},
+		"legacy translations removed by the plugin": {
+			newPost:          newTranslatedPost(p, 3, done, nil, true),
+			expectedRevision: 3,
+			expectedStatuses: done,
+		},
+		"unsigned statuses": {
+			newPost:              newTranslatedPost(p, 3, pending, legacy, false),
+			expectedRevision:     2,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"older signed revision": {
+			newPost:              newTranslatedPost(p, 1, pending, legacy, true),
+			expectedRevision:     2,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"legacy translations forged on a signed post": {
+			newPost:              newTranslatedPost(p, 2, done, map[string]interface{}{"es": "FORGED"}, true),
+			expectedRevision:     2,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"legacy translations forged along with a plugin update": {
+			newPost:              newTranslatedPost(p, 3, done, map[string]interface{}{"es": "FORGED"}, true),
+			expectedRevision:     3,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+		"legacy translations removed by a client": {
+			newPost:              newTranslatedPost(p, 2, done, nil, true),
+			expectedRevision:     2,
+			expectedStatuses:     done,
+			expectedTranslations: legacy,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post := p.protectTranslationProps(tc.newPost, oldPost)
+			if revision := getTranslationRevision(post); revision != tc.expectedRevision {
+				t.Errorf("expected revision %d, got %d", tc.expectedRevision, revision)
+			}
+			if statuses := getPostTranslationStatuses(post); !reflect.DeepEqual(statuses, tc.expectedStatuses) {
+				t.Errorf("expected statuses %v, got %v", tc.expectedStatuses, statuses)
+			}
+			if translations := post.GetProp(translationsProp); !reflect.DeepEqual(normalizeProp(translations), normalizeProp(tc.expectedTranslations)) {
+				t.Errorf("expected translations %v, got %v", tc.expectedTranslations, translations)
+			}
+		})
+	}
+}
+
+func TestIsPluginUpdate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		newRevision interface{}
+		oldRevision interface{}
+		expected    bool
+	}{
+		"no revisions":             {expected: false},
+		"same revision":            {newRevision: int64(2), oldRevision: int64(2), expected: false},
+		"increased revision":       {newRevision: int64(3), oldRevision: int64(2), expected: true},
+		"first revision":           {newRevision: int64(1), expected: true},
+		"older revision":           {newRevision: int64(1), oldRevision: int64(2), expected: false},
+		"revision read from json":  {newRevision: float64(3), oldRevision: float64(2), expected: true},
+		"revision dropped":         {oldRevision: int64(2), expected: false},
+		"revision of another type": {newRevision: "3", oldRevision: int64(2), expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			newPost := &model.Post{}
+			if tc.newRevision != nil {
+				newPost.AddProp(translationRevisionProp, tc.newRevision)
+			}
+			oldPost := &model.Post{}
+			if tc.oldRevision != nil {
+				oldPost.AddProp(translationRevisionProp, tc.oldRevision)
+			}
+
+			if result := isPluginUpdate(newPost, oldPost); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestHasValidShareSignature(t *testing.T) {
+	p := &Plugin{signingKey: []byte("signing key")}
+	signature := p.computeShareSignature("user", "channel", "root", "Hello", "Hola")
+
+	for name, tc := range map[string]struct {
+		signature   string
+		userID      string
+		channelID   string
+		rootID      string
+		original    string
+		translation string
+		expected    bool
+	}{
+		"as offered":                 {signature: signature, userID: "user", channelID: "channel", rootID: "root", original: "Hello", translation: "Hola", expected: true},
+		"no signature":               {userID: "user", channelID: "channel", rootID: "root", original: "Hello", translation: "Hola", expected: false},
+		"another user":               {signature: signature, userID: "other", channelID: "channel", rootID: "root", original: "Hello", translation: "Hola", expected: false},
+		"another channel":            {signature: signature, userID: "user", channelID: "other", rootID: "root", original: "Hello", translation: "Hola", expected: false},
+		"outside of the thread":      {signature: signature, userID: "user", channelID: "channel", original: "Hello", translation: "Hola", expected: false},
+		"made up original":           {signature: signature, userID: "user", channelID: "channel", rootID: "root", original: "Bye", translation: "Hola", expected: false},
+		"made up translation":        {signature: signature, userID: "user", channelID: "channel", rootID: "root", original: "Hello", translation: "Adiós", expected: false},
+		"parts moved between fields": {signature: p.computeShareSignature("user", "channel", "", "rootHello", "Hola"), userID: "user", channelID: "channel", rootID: "root", original: "Hello", translation: "Hola", expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if result := p.hasValidShareSignature(tc.signature, tc.userID, tc.channelID, tc.rootID, tc.original, tc.translation); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const (
+	translationKeyPrefix         = "translation"
+	translationIndexKeyPrefix    = "translation_langs"
+	personalTranslationKeyPrefix = "personal_translation"
+
+	// personalTranslationTTL is how long the translations users ask for themselves are kept.
+	personalTranslationTTL = 7 * 24 * time.Hour
+)
+
+// TranslatedContent is the translated text of a post in a language.
+type TranslatedContent struct {
+	Text string `json:"text"`
+	// Attachments are the translations of the message attachments of the post, if it has any.
+	Attachments []TranslatedAttachment `json:"attachments,omitempty"`
+	// Props are the translations of the props configured for the post type, keyed by path.
+	Props map[string]string `json:"props,omitempty"`
+}
+
+// Translation is a translation of a post message stored in the plugin KV store.
+type Translation struct {
+	TranslatedContent
+	UpdateAt int64 `json:"updateAt"`
+	// SourceHash identifies the version of the message that was translated.
+	SourceHash string `json:"sourceHash"`
+	// Provenance is unknown for translations moved from the props of earlier versions.
+	Provenance *TranslationProvenance `json:"provenance,omitempty"`
+}
+
+// hashMessage returns the hash used to tell the versions of a message apart.
+func hashMessage(message string) string {
+	sum := sha256.Sum256([]byte(message))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPostContent returns the hash used to tell the versions of the text of a post apart. Posts
+// with neither attachment text nor props to translate hash as their message alone.
+func (p *Plugin) hashPostContent(post *model.Post) string {
+	propTexts := p.getPropTexts(post)
+	if !hasAttachmentText(post) && len(propTexts) == 0 {
+		return hashMessage(post.Message)
+	}
+
+	parts := append([]string{post.Message}, getAttachmentTexts(post)...)
+	for _, propText := range propTexts {
+		parts = append(parts, propText.Path, propText.Text)
+	}
+	return hashMessage(strings.Join(parts, "\x00"))
+}
+
+// isCurrentTranslation checks if the translation was made for the current text of the post.
+func (p *Plugin) isCurrentTranslation(translation *Translation, post *model.Post) bool {
+	return translation.SourceHash == p.hashPostContent(post)
+}
+
+func getTranslationKey(postID, langCode string) string {
+	return fmt.Sprintf("%s_%s_%s", translationKeyPrefix, postID, langCode)
+}
+
+func getPersonalTranslationKey(userID, postID, langCode string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", personalTranslationKeyPrefix, userID, postID, langCode)
+}
+
+func getTranslationIndexKey(postID string) string {
+	return fmt.Sprintf("%s_%s", translationIndexKeyPrefix, postID)
+}
+
+// getTranslationIndex returns the languages the post has stored translations for.
+func (p *Plugin) getTranslationIndex(postID string) ([]string, error) {
+	languages := []string{}
+	if err := p.pluginAPI.KV.Get(getTranslationIndexKey(postID), &languages); err != nil {
+		return nil, fmt.Errorf("failed to get translation index: %w", err)
+	}
+	return languages, nil
+}
+
+// updateTranslationIndex atomically adds and removes languages from the index of the post.
+func (p *Plugin) updateTranslationIndex(postID string, added, removed []string) error {
+	return p.pluginAPI.KV.SetAtomicWithRetries(getTranslationIndexKey(postID), func(oldValue []byte) (interface{}, error) {
+		index := make(map[string]bool)
+		if oldValue != nil {
+			var languages []string
+			if err := json.Unmarshal(oldValue, &languages); err != nil {
+				return nil, err
+			}
+			for _, lang := range languages {
+				index[lang] = true
+			}
+		}
+		for _, lang := range added {
+			index[lang] = true
+		}
+		for _, lang := range removed {
+			delete(index, lang)
+		}
+
+		if len(index) == 0 {
+			return nil, nil
+		}
+		languages := make([]string, 0, len(index))
+		for lang := range index {
+			languages = append(languages, lang)
+		}
+		sort.Strings(languages)
+		return languages, nil
+	})
+}
+
+// saveTranslations stores the translations of the current text of a post, keyed by language,
+// along with how they were made.
+func (p *Plugin) saveTranslations(post *model.Post, translations map[string]TranslatedContent, provenance *TranslationProvenance) error {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	sourceHash := p.hashPostContent(post)
+	languages := make([]string, 0, len(translations))
+	for lang, content := range translations {
+		translation := Translation{
+			TranslatedContent: content,
+			UpdateAt:          model.GetMillis(),
+			SourceHash:        sourceHash,
+			Provenance:        provenance,
+		}
+		if _, err := p.pluginAPI.KV.Set(getTranslationKey(post.Id, lang), translation); err != nil {
+			return fmt.Errorf("failed to save translation: %w", err)
+		}
+		languages = append(languages, lang)
+	}
+
+	if err := p.updateTranslationIndex(post.Id, languages, nil); err != nil {
+		return fmt.Errorf("failed to update translation index: %w", err)
+	}
+	return nil
+}
+
+// deleteTranslations removes the stored translations of a post for the given languages.
+func (p *Plugin) deleteTranslations(postID string, languages []string) error {
+	if len(languages) == 0 {
+		return nil
+	}
+
+	for _, lang := range languages {
+		if err := p.pluginAPI.KV.Delete(getTranslationKey(postID, lang)); err != nil {
+			return fmt.Errorf("failed to delete translation: %w", err)
+		}
+	}
+
+	if err := p.updateTranslationIndex(postID, nil, languages); err != nil {
+		return fmt.Errorf("failed to update translation index: %w", err)
+	}
+	return nil
+}
+
+// getTranslation returns the stored translation of a post for a language, or nil if there is none.
+func (p *Plugin) getTranslation(postID, langCode string) (*Translation, error) {
+	var translation *Translation
+	if err := p.pluginAPI.KV.Get(getTranslationKey(postID, langCode), &translation); err != nil {
+		return nil, fmt.Errorf("failed to get translation: %w", err)
+	}
+	return translation, nil
+}
+
+// getStoredPostTranslations returns every stored translation of the current message of a post,
+// keyed by language.
+func (p *Plugin) getStoredPostTranslations(post *model.Post) (map[string]*Translation, error) {
+	languages, err := p.getTranslationIndex(post.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	translations := make(map[string]*Translation)
+	for _, lang := range languages {
+		translation, err := p.getTranslation(post.Id, lang)
+		if err != nil {
+			return nil, err
+		}
+		if translation != nil && p.isCurrentTranslation(translation, post) {
+			translations[lang] = translation
+		}
+	}
+	return translations, nil
+}
+
+// getStoredTranslations returns the translations of the current message of the given posts into a
+// language, keyed by post ID. Posts without such a translation are left out.
+func (p *Plugin) getStoredTranslations(posts []*model.Post, langCode string) (map[string]*Translation, error) {
+	translations := make(map[string]*Translation)
+	for _, post := range posts {
+		if _, ok := translations[post.Id]; ok {
+			continue
+		}
+
+		translation, err := p.getTranslation(post.Id, langCode)
+		if err != nil {
+			return nil, err
+		}
+		if translation != nil && p.isCurrentTranslation(translation, post) {
+			translations[post.Id] = translation
+		}
+	}
+	return translations, nil
+}
+
+// savePersonalTranslation stores a translation of the current content of a post that only the
+// user who asked for it sees. The shared post is left untouched.
+func (p *Plugin) savePersonalTranslation(userID string, post *model.Post, langCode string, content TranslatedContent, provenance *TranslationProvenance) error {
+	translation := Translation{
+		TranslatedContent: content,
+		UpdateAt:          model.GetMillis(),
+		SourceHash:        p.hashPostContent(post),
+		Provenance:        provenance,
+	}
+	if _, err := p.pluginAPI.KV.Set(getPersonalTranslationKey(userID, post.Id, langCode), translation, pluginapi.SetExpiry(personalTranslationTTL)); err != nil {
+		return fmt.Errorf("failed to save personal translation: %w", err)
+	}
+	return nil
+}
+
+// getPersonalTranslations returns the translations of the current message of the given posts into a
+// language that the user asked for themselves, keyed by post ID.
+func (p *Plugin) getPersonalTranslations(userID string, posts []*model.Post, langCode string) (map[string]*Translation, error) {
+	translations := make(map[string]*Translation)
+	for _, post := range posts {
+		if _, ok := translations[post.Id]; ok {
+			continue
+		}
+
+		var translation *Translation
+		if err := p.pluginAPI.KV.Get(getPersonalTranslationKey(userID, post.Id, langCode), &translation); err != nil {
+			return nil, fmt.Errorf("failed to get personal translation: %w", err)
+		}
+		if translation != nil && p.isCurrentTranslation(translation, post) {
+			translations[post.Id] = translation
+		}
+	}
+	return translations, nil
+}
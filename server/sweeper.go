@@ -0,0 +1,173 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const (
+	sweeperJobKey       = "stuck_translations_sweeper"
+	sweeperAttemptsKey  = "translation_sweep_attempts"
+	sweeperInterval     = 5 * time.Minute
+	stuckTranslationAge = 10 * time.Minute
+	sweeperLookback     = time.Hour
+	maxSweeperAttempts  = 3
+)
+
+// getSweeperAttemptsKey returns the key the sweeps of a version of the post are counted in, so an
+// edit starts counting again.
+func (p *Plugin) getSweeperAttemptsKey(post *model.Post) string {
+	return fmt.Sprintf("%s_%s_%s", sweeperAttemptsKey, post.Id, p.hashPostContent(post))
+}
+
+// getPendingSince returns when the post was last written. Edits and retries set its languages back
+// to pending, so this is when they started waiting at the latest.
+func getPendingSince(post *model.Post) int64 {
+	return max(post.EditAt, post.UpdateAt)
+}
+
+// listTranslationChannels returns the IDs of the channels that have translations enabled, or
+// that ever had them enabled when onlyEnabled is false.
+func (p *Plugin) listTranslationChannels(onlyEnabled bool) ([]string, error) {
+	prefix := translationEnabledKey + "_"
+	channelIDs := []string{}
+	for page := 0; ; page++ {
+		keys, err := p.pluginAPI.KV.ListKeys(page, 1000, pluginapi.WithPrefix(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list translation channels: %w", err)
+		}
+
+		for _, key := range keys {
+			channelID := strings.TrimPrefix(key, prefix)
+			if !onlyEnabled {
+				channelIDs = append(channelIDs, channelID)
+				continue
+			}
+
+			enabled, err := p.isChannelTranslationEnabled(channelID)
+			if err != nil {
+				return nil, err
+			}
+			if enabled {
+				channelIDs = append(channelIDs, channelID)
+			}
+		}
+
+		if len(keys) < 1000 {
+			return channelIDs, nil
+		}
+	}
+}
+
+// sweepStuckTranslations finds recent posts that are still waiting for some of their
+// translations, for example because the server stopped while they were being translated, and
+// either queues the missing languages again or marks them as failed once the retries run out.
+func (p *Plugin) sweepStuckTranslations() {
+	if !p.getConfiguration().EnableTranslations {
+		return
+	}
+
+	channelIDs, err := p.listTranslationChannels(true)
+	if err != nil {
+		p.pluginAPI.Log.Error("Failed to sweep stuck translations", "error", err.Error())
+		return
+	}
+
+	languages := p.getTranslationLanguages()
+	now := time.Now()
+	since := model.GetMillisForTime(now.Add(-sweeperLookback))
+	stuckBefore := model.GetMillisForTime(now.Add(-stuckTranslationAge))
+
+	requeued := []string{}
+	failed := []string{}
+	for _, channelID := range channelIDs {
+		postList, err := p.pluginAPI.Post.GetPostsSince(channelID, since)
+		if err != nil {
+			p.pluginAPI.Log.Warn("Failed to get posts to sweep", "channel_id", channelID, "error", err.Error())
+			continue
+		}
+
+		for _, post := range postList.Posts {
+			if !hasTranslationState(post) || post.DeleteAt != 0 || getPendingSince(post) > stuckBefore || !p.isTranslatedPostType(post.Type) {
+				continue
+			}
+
+			missing := getMissingLanguages(post, languages)
+			if len(missing) == 0 {
+				continue
+			}
+
+			requeue, err := p.registerSweeperAttempt(post)
+			if err != nil {
+				p.pluginAPI.Log.Warn("Failed to record sweeper attempt", "post_id", post.Id, "error", err.Error())
+				continue
+			}
+
+			if requeue && p.isAutoTranslationPaused(post.ChannelId) {
+				if err := p.markLanguagesFailed(post, missing, "translation budget exceeded"); err != nil {
+					p.pluginAPI.Log.Warn("Failed to mark paused translations as failed", "post_id", post.Id, "error", err.Error())
+					continue
+				}
+				failed = append(failed, post.Id)
+				continue
+			}
+
+			if requeue {
+				requeued = append(requeued, post.Id)
+				p.translatePost(post, nil, post.UserId, requestedByAuto, missing)
+				continue
+			}
+
+			if err := p.markLanguagesFailed(post, missing, "translation timed out"); err != nil {
+				p.pluginAPI.Log.Warn("Failed to mark stuck translations as failed", "post_id", post.Id, "error", err.Error())
+				continue
+			}
+			failed = append(failed, post.Id)
+		}
+	}
+
+	if len(requeued) > 0 || len(failed) > 0 {
+		p.pluginAPI.Log.Info("Repaired stuck translations",
+			"requeued_posts", strings.Join(requeued, ","),
+			"failed_posts", strings.Join(failed, ","),
+		)
+	}
+}
+
+// registerSweeperAttempt counts a new sweep of the current version of the post and reports whether
+// its missing languages should be queued again.
+func (p *Plugin) registerSweeperAttempt(post *model.Post) (bool, error) {
+	key := p.getSweeperAttemptsKey(post)
+	var attempts int
+	if err := p.pluginAPI.KV.Get(key, &attempts); err != nil {
+		return false, err
+	}
+
+	if attempts >= maxSweeperAttempts {
+		return false, nil
+	}
+
+	if _, err := p.pluginAPI.KV.Set(key, attempts+1, pluginapi.SetExpiry(2*sweeperLookback)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// markLanguagesFailed stores a failed status with the given reason for the languages of the post.
+func (p *Plugin) markLanguagesFailed(post *model.Post, languages []string, reason string) error {
+	return p.updatePostTranslations(post, func(current *model.Post) error {
+		statuses := getPostTranslationStatuses(current)
+		for _, lang := range languages {
+			statuses[lang] = LanguageStatus{Status: TranslationStatusFailed, Reason: reason}
+		}
+		current.AddProp(translationStatusProp, statuses)
+		return nil
+	})
+}
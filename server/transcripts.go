@@ -0,0 +1,150 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// transcriptBatchSize is how many cues are translated in a single request.
+const transcriptBatchSize = 20
+
+// cueMarkerPattern matches the lines the cues of a batch are numbered with.
+var cueMarkerPattern = regexp.MustCompile(`(?m)^\s*\[\[(\d+)\]\]\s*$`)
+
+// transcriptBlock is a block of a WebVTT or SRT file. Cues keep their ID and timing lines in the
+// head and only their text is translated, while other blocks such as the WEBVTT header and notes
+// are kept whole in the head.
+type transcriptBlock struct {
+	Head []string
+	Text []string
+}
+
+// isTranscriptFile checks if the file is a WebVTT or SRT transcript.
+func isTranscriptFile(info *model.FileInfo) bool {
+	extension := strings.ToLower(info.Extension)
+	return extension == "vtt" || extension == "srt"
+}
+
+// parseTranscript splits a WebVTT or SRT file into its blocks.
+func parseTranscript(content string) []*transcriptBlock {
+	content = strings.TrimPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "\ufeff")
+
+	blocks := []*transcriptBlock{}
+	for _, raw := range strings.Split(content, "\n\n") {
+		raw = strings.Trim(raw, "\n")
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+
+		lines := strings.Split(raw, "\n")
+		block := &transcriptBlock{Head: lines}
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				block.Head, block.Text = lines[:i+1], lines[i+1:]
+				break
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// formatTranscript writes the blocks back into a WebVTT or SRT file.
+func formatTranscript(blocks []*transcriptBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		parts = append(parts, strings.Join(append(append([]string{}, block.Head...), block.Text...), "\n"))
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// getTranscriptCues returns the blocks of the transcript that are cues with text.
+func getTranscriptCues(blocks []*transcriptBlock) []*transcriptBlock {
+	cues := []*transcriptBlock{}
+	for _, block := range blocks {
+		if len(block.Text) > 0 {
+			cues = append(cues, block)
+		}
+	}
+	return cues
+}
+
+// countTranscriptBatches returns how many requests translating the transcript takes.
+func countTranscriptBatches(blocks []*transcriptBlock) int {
+	return (len(getTranscriptCues(blocks)) + transcriptBatchSize - 1) / transcriptBatchSize
+}
+
+// translateTranscript translates the text of the cues of the transcript in place, a batch of cues
+// per request. The cues of a batch are numbered so their translations can be told apart, and a
+// batch whose translation can't be lined up with its cues is translated again one cue at a time.
+func (p *Plugin) translateTranscript(blocks []*transcriptBlock, requestorID, channelID, langCode string) error {
+	cues := getTranscriptCues(blocks)
+	for start := 0; start < len(cues); start += transcriptBatchSize {
+		batch := cues[start:min(start+transcriptBatchSize, len(cues))]
+
+		parts := make([]string, 0, len(batch))
+		for i, cue := range batch {
+			parts = append(parts, fmt.Sprintf("[[%d]]\n%s", i+1, strings.Join(cue.Text, "\n")))
+		}
+		translation, err := p.translateWithRetry(strings.Join(parts, "\n"), requestorID, channelID, langCode)
+		if err != nil {
+			return fmt.Errorf("failed to translate cues: %w", err)
+		}
+
+		texts, ok := splitCueTranslations(translation, len(batch))
+		if !ok {
+			p.pluginAPI.Log.Debug("Translating transcript cues one at a time", "channel_id", channelID, "language", langCode)
+			for i, cue := range batch {
+				texts[i], err = p.translateWithRetry(strings.Join(cue.Text, "\n"), requestorID, channelID, langCode)
+				if err != nil {
+					return fmt.Errorf("failed to translate cue: %w", err)
+				}
+			}
+		}
+
+		// A blank line would end the cue, so the lines of its translation are kept together
+		for i, cue := range batch {
+			cue.Text = []string{}
+			for _, line := range strings.Split(strings.TrimSpace(texts[i]), "\n") {
+				if strings.TrimSpace(line) != "" {
+					cue.Text = append(cue.Text, line)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// splitCueTranslations splits the translation of a batch of numbered cues. It returns false when
+// the translation doesn't hold every cue in order.
+func splitCueTranslations(translation string, count int) ([]string, bool) {
+	texts := make([]string, count)
+	markers := cueMarkerPattern.FindAllStringSubmatchIndex(translation, -1)
+	if len(markers) != count {
+		return texts, false
+	}
+
+	for i, marker := range markers {
+		number, err := strconv.Atoi(translation[marker[2]:marker[3]])
+		if err != nil || number != i+1 {
+			return texts, false
+		}
+
+		end := len(translation)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		texts[i] = strings.TrimSpace(translation[marker[1]:end])
+		if texts[i] == "" {
+			return texts, false
+		}
+	}
+	return texts, true
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTranscript(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content  string
+		expected []*transcriptBlock
+		// formatted is what the blocks are written back as, when it isn't the content itself
+		formatted string
+	}{
+		"empty file": {
+			content:   "",
+			expected:  []*transcriptBlock{},
+			formatted: "\n",
+		},
+		"srt": {
+			content: "1\n00:00:01,000 --> 00:00:02,500\nHello\nworld\n\n2\n00:00:03,000 --> 00:00:04,000\nBye\n",
+			expected: []*transcriptBlock{
+				{Head: []string{"1", "00:00:01,000 --> 00:00:02,500"}, Text: []string{"Hello", "world"}},
+				{Head: []string{"2", "00:00:03,000 --> 00:00:04,000"}, Text: []string{"Bye"}},
+			},
+		},
+		"vtt with note and style blocks": {
+			content: "WEBVTT\n\nSTYLE\n::cue {\n  color: yellow;\n}\n\nNOTE recorded on Monday\n\nintro\n00:00:01.000 --> 00:00:02.000 align:start\n<v Ann>Welcome\n\n00:00:03.000 --> 00:00:04.000\nThanks\n",
+			expected: []*transcriptBlock{
+				{Head: []string{"WEBVTT"}},
+				{Head: []string{"STYLE", "::cue {", "  color: yellow;", "}"}},
+				{Head: []string{"NOTE recorded on Monday"}},
+				{Head: []string{"intro", "00:00:01.000 --> 00:00:02.000 align:start"}, Text: []string{"<v Ann>Welcome"}},
+				{Head: []string{"00:00:03.000 --> 00:00:04.000"}, Text: []string{"Thanks"}},
+			},
+		},
+		"byte order mark, windows line endings and extra blank lines": {
+			content: "\ufeffWEBVTT\r\n\r\n\r\n\r\n00:00:01.000 --> 00:00:02.000\r\nHi\r\n",
+			expected: []*transcriptBlock{
+				{Head: []string{"WEBVTT"}},
+				{Head: []string{"00:00:01.000 --> 00:00:02.000"}, Text: []string{"Hi"}},
+			},
+			formatted: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHi\n",
+		},
+		"cue without text": {
+			content: "1\n00:00:01,000 --> 00:00:02,000\n",
+			expected: []*transcriptBlock{
+				{Head: []string{"1", "00:00:01,000 --> 00:00:02,000"}, Text: []string{}},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			blocks := parseTranscript(tc.content)
+			if !reflect.DeepEqual(blocks, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, blocks)
+			}
+
+			formatted := tc.formatted
+			if formatted == "" {
+				formatted = tc.content
+			}
+			if result := formatTranscript(blocks); result != formatted {
+				t.Errorf("expected %q, got %q", formatted, result)
+			}
+		})
+	}
+}
+
+func TestCountTranscriptBatches(t *testing.T) {
+	cue := "00:00:01.000 --> 00:00:02.000\ntext"
+	for name, tc := range map[string]struct {
+		cues     int
+		expected int
+	}{
+		"no cues":              {cues: 0, expected: 0},
+		"one cue":              {cues: 1, expected: 1},
+		"full batch":           {cues: transcriptBatchSize, expected: 1},
+		"one more than batch":  {cues: transcriptBatchSize + 1, expected: 2},
+		"several full batches": {cues: 3 * transcriptBatchSize, expected: 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			parts := []string{"WEBVTT", "NOTE not a cue"}
+			for i := 0; i < tc.cues; i++ {
+				parts = append(parts, cue)
+			}
+
+			if count := countTranscriptBatches(parseTranscript(strings.Join(parts, "\n\n"))); count != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, count)
+			}
+		})
+	}
+}
+
+func TestSplitCueTranslations(t *testing.T) {
+	for name, tc := range map[string]struct {
+		translation string
+		count       int
+		expected    []string
+		expectedOk  bool
+	}{
+		"every cue in order": {
+			translation: "[[1]]\nHola\nmundo\n[[2]]\nAdiós\n",
+			count:       2,
+			expected:    []string{"Hola\nmundo", "Adiós"},
+			expectedOk:  true,
+		},
+		"markers with surrounding spaces": {
+			translation: "  [[1]]  \nHola\n\n [[2]]\nAdiós",
+			count:       2,
+			expected:    []string{"Hola", "Adiós"},
+			expectedOk:  true,
+		},
+		"missing marker": {
+			translation: "[[1]]\nHola\nAdiós",
+			count:       2,
+			expectedOk:  false,
+		},
+		"extra marker": {
+			translation: "[[1]]\nHola\n[[2]]\nAdiós\n[[3]]\nOtra",
+			count:       2,
+			expectedOk:  false,
+		},
+		"markers out of order": {
+			translation: "[[2]]\nAdiós\n[[1]]\nHola",
+			count:       2,
+			expectedOk:  false,
+		},
+		"skipped number": {
+			translation: "[[1]]\nHola\n[[3]]\nAdiós",
+			count:       2,
+			expectedOk:  false,
+		},
+		"marker inside a line is not a marker": {
+			translation: "[[1]]\nHola [[2]] Adiós",
+			count:       2,
+			expectedOk:  false,
+		},
+		"cue without translation": {
+			translation: "[[1]]\n[[2]]\nAdiós",
+			count:       2,
+			expectedOk:  false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			texts, ok := splitCueTranslations(tc.translation, tc.count)
+			if ok != tc.expectedOk {
+				t.Fatalf("expected ok: %v, got %v", tc.expectedOk, ok)
+			}
+			if len(texts) != tc.count {
+				t.Fatalf("expected %d texts, got %d", tc.count, len(texts))
+			}
+			if ok && !reflect.DeepEqual(texts, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, texts)
+			}
+		})
+	}
+}
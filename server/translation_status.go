@@ -0,0 +1,129 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	translationsProp      = "translations"
+	translationStatusProp = "translation_status"
+	// translationRevisionProp is increased every time the plugin writes the translations of a
+	// post, which tells the plugin's own updates apart from the ones made by others.
+	translationRevisionProp = "translation_revision"
+
+	// originalMessageProp keeps the text a post was translated from, for posts whose author
+	// posted a translation instead of their own text.
+	originalMessageProp = "original_message"
+
+	translationProgressEvent = "translation_progress"
+
+	// legacyTranslationPostType is the post type earlier versions of the plugin replaced the type of
+	// translated posts with.
+	legacyTranslationPostType = "custom_translation"
+
+	TranslationStatusPending = "pending"
+	TranslationStatusDone    = "done"
+	TranslationStatusFailed  = "failed"
+	TranslationStatusSkipped = "skipped"
+)
+
+// LanguageStatus is the translation state of a single language of a post.
+type LanguageStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// hasTranslationState checks if the plugin tracks the translations of the post.
+func hasTranslationState(post *model.Post) bool {
+	return post.GetProp(translationStatusProp) != nil || post.Type == legacyTranslationPostType
+}
+
+// getPostTranslations returns a copy of the translations that earlier versions of the plugin stored
+// in the post props. New translations are kept in the translation store instead.
+func getPostTranslations(post *model.Post) map[string]interface{} {
+	translations := make(map[string]interface{})
+	if existing, ok := post.GetProp(translationsProp).(map[string]interface{}); ok {
+		for lang, text := range existing {
+			translations[lang] = text
+		}
+	}
+	return translations
+}
+
+// getPostTranslationStatuses returns a copy of the per-language statuses stored in the post
+// props. The props may hold either the decoded JSON or the typed map written by this plugin.
+func getPostTranslationStatuses(post *model.Post) map[string]LanguageStatus {
+	statuses := make(map[string]LanguageStatus)
+	raw := post.GetProp(translationStatusProp)
+	if raw == nil {
+		return statuses
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return statuses
+	}
+	_ = json.Unmarshal(data, &statuses)
+	return statuses
+}
+
+// getFailedLanguages returns the languages of the post whose translation failed.
+func getFailedLanguages(post *model.Post) []string {
+	failed := []string{}
+	for lang, status := range getPostTranslationStatuses(post) {
+		if status.Status == TranslationStatusFailed {
+			failed = append(failed, lang)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// getMissingLanguages returns the languages of the post that have not reached a final status yet.
+// Posts written before statuses were tracked are considered complete for a language once it has
+// a translation.
+func getMissingLanguages(post *model.Post, languages []string) []string {
+	translations := getPostTranslations(post)
+	statuses := getPostTranslationStatuses(post)
+
+	missing := []string{}
+	for _, lang := range languages {
+		if status, ok := statuses[lang]; ok {
+			if status.Status == TranslationStatusPending {
+				missing = append(missing, lang)
+			}
+			continue
+		}
+		if _, ok := translations[lang]; !ok {
+			missing = append(missing, lang)
+		}
+	}
+	return missing
+}
+
+// getTranslationRevision returns how many times the plugin wrote the translations of the post.
+func getTranslationRevision(post *model.Post) int64 {
+	switch revision := post.GetProp(translationRevisionProp).(type) {
+	case float64:
+		return int64(revision)
+	case int64:
+		return revision
+	case int:
+		return int64(revision)
+	default:
+		return 0
+	}
+}
+
+// isPluginUpdate checks if an update of a post was written by the plugin itself. Only the plugin
+// increases the revision, others either keep it or write back an older one they read before.
+// Updates from others that change the revision are reverted by protectTranslationProps first.
+func isPluginUpdate(newPost, oldPost *model.Post) bool {
+	return getTranslationRevision(newPost) > getTranslationRevision(oldPost)
+}
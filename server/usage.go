@@ -0,0 +1,238 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	costKeyPrefix = "cost"
+
+	usageScopeChannel  = "channel"
+	usageScopeTeam     = "team"
+	usageScopeLanguage = "language"
+
+	// usageRetention is how long the usage records are kept.
+	usageRetention = 400 * 24 * time.Hour
+
+	// budgetWarningPercent is the share of a budget after which channel admins are warned.
+	budgetWarningPercent = 80
+
+	monthFormat = "2006-01"
+)
+
+// TranslationUsage is the usage of the translation backend over a period of time. Tokens are
+// estimated from the length of the prompts and the translations, as the backend doesn't report
+// them.
+type TranslationUsage struct {
+	Requests     int64 `json:"requests"`
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+}
+
+// totalTokens returns the tokens used both ways.
+func (u TranslationUsage) totalTokens() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+// estimateTokens estimates the tokens of a text, taking about four characters per token.
+func estimateTokens(text string) int64 {
+	return int64(utf8.RuneCountInString(text)+3) / 4
+}
+
+// getCostKey returns the key of the usage of a channel, team or language over a period, which
+// is either a day or a month.
+func getCostKey(scope, id, period string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", costKeyPrefix, scope, id, period)
+}
+
+// addUsage atomically adds usage to a record, returning the record before and after.
+func (p *Plugin) addUsage(key string, usage TranslationUsage) (TranslationUsage, TranslationUsage, error) {
+	var before, after TranslationUsage
+	err := p.setAtomicWithExpiry(key, usageRetention, func(oldValue []byte) (interface{}, error) {
+		before = TranslationUsage{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &before); err != nil {
+				return nil, err
+			}
+		}
+
+		after = TranslationUsage{
+			Requests:     before.Requests + usage.Requests,
+			InputTokens:  before.InputTokens + usage.InputTokens,
+			OutputTokens: before.OutputTokens + usage.OutputTokens,
+		}
+		return after, nil
+	})
+	if err != nil {
+		return TranslationUsage{}, TranslationUsage{}, fmt.Errorf("failed to record translation usage: %w", err)
+	}
+	return before, after, nil
+}
+
+// getUsage returns a usage record, empty if nothing was recorded.
+func (p *Plugin) getUsage(scope, id, period string) (TranslationUsage, error) {
+	var usage TranslationUsage
+	if err := p.pluginAPI.KV.Get(getCostKey(scope, id, period), &usage); err != nil {
+		return TranslationUsage{}, fmt.Errorf("failed to get translation usage: %w", err)
+	}
+	return usage, nil
+}
+
+// recordTranslationUsage charges a request to the translation backend to the channel, its team
+// and the language, per day and per month, and checks the monthly budgets.
+func (p *Plugin) recordTranslationUsage(channelID, langCode string, usage TranslationUsage) {
+	if channelID == "" {
+		return
+	}
+
+	teamID := ""
+	if channel, err := p.pluginAPI.Channel.Get(channelID); err == nil {
+		teamID = channel.TeamId
+	}
+
+	now := time.Now().UTC()
+	day := now.Format(time.DateOnly)
+	month := now.Format(monthFormat)
+
+	keys := []string{
+		getCostKey(usageScopeChannel, channelID, day),
+		getCostKey(usageScopeLanguage, langCode, day),
+	}
+	if teamID != "" {
+		keys = append(keys, getCostKey(usageScopeTeam, teamID, day))
+	}
+	for _, key := range keys {
+		if _, _, err := p.addUsage(key, usage); err != nil {
+			p.pluginAPI.Log.Warn("Failed to record translation usage", "key", key, "error", err.Error())
+		}
+	}
+
+	config := p.getConfiguration()
+	before, after, err := p.addUsage(getCostKey(usageScopeChannel, channelID, month), usage)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to record monthly translation usage", "channel_id", channelID, "error", err.Error())
+	} else {
+		p.checkBudget(channelID, "channel", before, after, config.ChannelMonthlyTokenBudget)
+	}
+
+	if teamID == "" {
+		return
+	}
+	before, after, err = p.addUsage(getCostKey(usageScopeTeam, teamID, month), usage)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to record monthly translation usage", "team_id", teamID, "error", err.Error())
+	} else {
+		p.checkBudget(channelID, "team", before, after, config.TeamMonthlyTokenBudget)
+	}
+}
+
+// checkBudget warns the admins of the channel when the usage of the channel or its team crosses
+// the warning threshold or the whole monthly budget.
+func (p *Plugin) checkBudget(channelID, scope string, before, after TranslationUsage, budget int) {
+	if budget <= 0 {
+		return
+	}
+
+	limit := int64(budget)
+	warnAt := limit * budgetWarningPercent / 100
+	switch {
+	case before.totalTokens() < limit && after.totalTokens() >= limit:
+		message := fmt.Sprintf("The translations of this %s went over their monthly budget of %d tokens.", scope, budget)
+		if p.getConfiguration().PauseOverBudget {
+			message += " Automatic translations are paused until next month."
+		}
+		p.warnChannelAdmins(channelID, message)
+	case before.totalTokens() < warnAt && after.totalTokens() >= warnAt:
+		p.warnChannelAdmins(channelID, fmt.Sprintf("The translations of this %s used %d%% of their monthly budget of %d tokens.", scope, budgetWarningPercent, budget))
+	}
+}
+
+// warnChannelAdmins sends a direct message from the plugin bot to the admins of the channel.
+func (p *Plugin) warnChannelAdmins(channelID, message string) {
+	channel, err := p.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		p.pluginAPI.Log.Warn("Failed to get channel to warn its admins", "channel_id", channelID, "error", err.Error())
+		return
+	}
+
+	text := fmt.Sprintf("%s\nChannel: ~%s", message, channel.Name)
+	perPage := 100
+	for page := 0; ; page++ {
+		members, err := p.pluginAPI.Channel.ListMembers(channelID, page, perPage)
+		if err != nil {
+			p.pluginAPI.Log.Warn("Failed to list channel members to warn", "channel_id", channelID, "error", err.Error())
+			return
+		}
+
+		for _, member := range members {
+			if !member.SchemeAdmin {
+				continue
+			}
+			direct, err := p.pluginAPI.Channel.GetDirect(p.botID, member.UserId)
+			if err != nil {
+				p.pluginAPI.Log.Warn("Failed to get direct channel", "user_id", member.UserId, "error", err.Error())
+				continue
+			}
+			if err := p.pluginAPI.Post.CreatePost(&model.Post{UserId: p.botID, ChannelId: direct.Id, Message: text}); err != nil {
+				p.pluginAPI.Log.Warn("Failed to warn channel admin", "user_id", member.UserId, "error", err.Error())
+			}
+		}
+
+		if len(members) < perPage {
+			return
+		}
+	}
+}
+
+// isAutoTranslationPaused checks if the channel, or its team, went over the monthly budget and
+// automatic translations are paused because of it.
+func (p *Plugin) isAutoTranslationPaused(channelID string) bool {
+	config := p.getConfiguration()
+	if !config.PauseOverBudget {
+		return false
+	}
+
+	month := time.Now().UTC().Format(monthFormat)
+	if config.ChannelMonthlyTokenBudget > 0 {
+		usage, err := p.getUsage(usageScopeChannel, channelID, month)
+		if err == nil && usage.totalTokens() >= int64(config.ChannelMonthlyTokenBudget) {
+			return true
+		}
+	}
+
+	if config.TeamMonthlyTokenBudget > 0 {
+		channel, err := p.pluginAPI.Channel.Get(channelID)
+		if err != nil || channel.TeamId == "" {
+			return false
+		}
+		usage, err := p.getUsage(usageScopeTeam, channel.TeamId, month)
+		if err == nil && usage.totalTokens() >= int64(config.TeamMonthlyTokenBudget) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMonthlyUsage returns the usage of a channel, team or language over a month, day by day.
+func (p *Plugin) getMonthlyUsage(scope, id string, month time.Time) (map[string]TranslationUsage, error) {
+	days := make(map[string]TranslationUsage)
+	for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+		period := day.Format(time.DateOnly)
+		usage, err := p.getUsage(scope, id, period)
+		if err != nil {
+			return nil, err
+		}
+		if usage.Requests > 0 {
+			days[period] = usage
+		}
+	}
+	return days, nil
+}
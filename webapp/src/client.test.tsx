@@ -5,6 +5,13 @@ import {
     getChannelTranslationStatus,
     toggleChannelTranslations,
     translatePost,
+    getPostTranslations,
+    getTranslationsBatch,
+    loadPostTranslation,
+    retryPostTranslations,
+    translatePostFile,
+    getChannelInfoTranslation,
+    getFileTranslations,
     getTranslationLanguages,
     setUserTranslationLanguage,
 } from './client';
@@ -79,7 +86,235 @@ describe('client', () => {
                 expectedUrl,
                 expect.objectContaining({
                     method: 'POST',
-                    body: JSON.stringify({lang}),
+                    body: JSON.stringify({lang, permanent: false}),
+                }),
+            );
+        });
+
+        test('should ask for a permanent translation', async () => {
+            // Act
+            await translatePost('post123', 'es', true);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                `/plugins/${manifest.id}/post/post123/translate`,
+                expect.objectContaining({
+                    body: JSON.stringify({lang: 'es', permanent: true}),
+                }),
+            );
+        });
+    });
+
+    describe('getPostTranslations', () => {
+        test('should make GET request to correct URL', async () => {
+            // Arrange
+            const postId = 'post123';
+            const expectedUrl = `/plugins/${manifest.id}/post/${postId}/translations`;
+
+            // Act
+            await getPostTranslations(postId);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'GET',
+                }),
+            );
+        });
+    });
+
+    describe('getTranslationsBatch', () => {
+        test('should make POST request with post ids and language', async () => {
+            // Arrange
+            const postIds = ['post1', 'post2'];
+            const lang = 'es';
+            const expectedUrl = `/plugins/${manifest.id}/translations/batch`;
+
+            // Act
+            await getTranslationsBatch(postIds, lang, true);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'POST',
+                    body: JSON.stringify({post_ids: postIds, lang, generate: true}),
+                }),
+            );
+        });
+    });
+
+    describe('loadPostTranslation', () => {
+        test('should group posts requested together into one batch request', async () => {
+            // Arrange
+            (global.fetch as jest.Mock).mockResolvedValue({
+                ok: true,
+                json: jest.fn().mockResolvedValue({translations: {post1: 'Hola', post2: 'Adiós'}, missing: []}),
+            });
+
+            // Act
+            const results = await Promise.all([
+                loadPostTranslation('post1', 'es'),
+                loadPostTranslation('post2', 'es'),
+            ]);
+
+            // Assert
+            expect(results).toEqual([
+                {text: 'Hola', attachments: undefined, props: undefined},
+                {text: 'Adiós', attachments: undefined, props: undefined},
+            ]);
+            expect(global.fetch).toHaveBeenCalledTimes(1);
+            expect(global.fetch).toHaveBeenCalledWith(
+                `/plugins/${manifest.id}/translations/batch`,
+                expect.objectContaining({
+                    body: JSON.stringify({post_ids: ['post1', 'post2'], lang: 'es', generate: false}),
+                }),
+            );
+        });
+    });
+
+    describe('loadPostTranslation with many posts', () => {
+        test('should split the posts into batches the server accepts', async () => {
+            // Arrange
+            (global.fetch as jest.Mock).mockImplementation((url: string, options: any) => {
+                const {post_ids: postIds} = JSON.parse(options.body);
+                const translations: Record<string, string> = {};
+                postIds.forEach((postId: string) => {
+                    translations[postId] = `translated ${postId}`;
+                });
+                return Promise.resolve({ok: true, json: jest.fn().mockResolvedValue({translations, missing: []})});
+            });
+            const postIds = Array.from({length: 250}, (_, i) => `post${i}`);
+
+            // Act
+            const results = await Promise.all(postIds.map((postId) => loadPostTranslation(postId, 'de')));
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledTimes(2);
+            expect(JSON.parse((global.fetch as jest.Mock).mock.calls[0][1].body).post_ids).toHaveLength(200);
+            expect(JSON.parse((global.fetch as jest.Mock).mock.calls[1][1].body).post_ids).toHaveLength(50);
+            expect(results[249]?.text).toBe('translated post249');
+        });
+    });
+
+    describe('loadPostTranslation with attachments and props', () => {
+        test('should return the translated attachments and props along with the message', async () => {
+            // Arrange
+            (global.fetch as jest.Mock).mockResolvedValue({
+                ok: true,
+                json: jest.fn().mockResolvedValue({
+                    translations: {post1: ''},
+                    missing: ['post2'],
+                    attachments: {post1: [{title: 'Alerta'}]},
+                    props: {post1: {'card.title': 'Tarjeta'}},
+                }),
+            });
+
+            // Act
+            const results = await Promise.all([
+                loadPostTranslation('post1', 'es'),
+                loadPostTranslation('post2', 'es'),
+            ]);
+
+            // Assert
+            expect(results).toEqual([
+                {text: '', attachments: [{title: 'Alerta'}], props: {'card.title': 'Tarjeta'}},
+                undefined,
+            ]);
+        });
+    });
+
+    describe('retryPostTranslations', () => {
+        test('should make POST request to correct URL', async () => {
+            // Arrange
+            const postId = 'post123';
+            const expectedUrl = `/plugins/${manifest.id}/post/${postId}/translations/retry`;
+
+            // Act
+            await retryPostTranslations(postId);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'POST',
+                }),
+            );
+        });
+    });
+
+    describe('translatePostFile', () => {
+        test('should make POST request to correct URL with the language', async () => {
+            // Arrange
+            const postId = 'post123';
+            const fileId = 'file123';
+            const expectedUrl = `/plugins/${manifest.id}/post/${postId}/file/${fileId}/translate`;
+
+            // Act
+            await translatePostFile(postId, fileId, 'es');
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'POST',
+                    body: JSON.stringify({lang: 'es'}),
+                }),
+            );
+        });
+    });
+
+    describe('getChannelInfoTranslation', () => {
+        test('should make GET request for the language of the user', async () => {
+            // Arrange
+            const channelId = 'channel123';
+            const expectedUrl = `/plugins/${manifest.id}/channel/${channelId}/info_translation`;
+
+            // Act
+            await getChannelInfoTranslation(channelId);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'GET',
+                }),
+            );
+        });
+
+        test('should make GET request for the given language', async () => {
+            // Arrange
+            const channelId = 'channel123';
+            const expectedUrl = `/plugins/${manifest.id}/channel/${channelId}/info_translation?lang=es`;
+
+            // Act
+            await getChannelInfoTranslation(channelId, 'es');
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'GET',
+                }),
+            );
+        });
+    });
+
+    describe('getFileTranslations', () => {
+        test('should make GET request to correct URL', async () => {
+            // Arrange
+            const fileId = 'file123';
+            const expectedUrl = `/plugins/${manifest.id}/file/${fileId}/translations`;
+
+            // Act
+            await getFileTranslations(fileId);
+
+            // Assert
+            expect(global.fetch).toHaveBeenCalledWith(
+                expectedUrl,
+                expect.objectContaining({
+                    method: 'GET',
                 }),
             );
         });
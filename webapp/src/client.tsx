@@ -57,16 +57,132 @@ export async function getChannelTranslationStatus(channelId: string) {
     return doGet(url);
 }
 
+// getChannelInfoTranslation returns the header and purpose of the channel in the language, or in the
+// user's language when none is given.
+export async function getChannelInfoTranslation(channelId: string, lang?: string) {
+    const query = lang ? `?lang=${encodeURIComponent(lang)}` : '';
+    const url = `${channelRoute(channelId)}/info_translation${query}`;
+    return doGet(url);
+}
+
 export async function toggleChannelTranslations(channelId: string, enabled: boolean) {
     const url = `${channelRoute(channelId)}/translations`;
     return doPost(url, {enabled});
 }
 
-export async function translatePost(postId: string, lang: string) {
+// translatePost translates the post on demand. Unless permanent, the translation is kept only for
+// the current user and the post is left untouched.
+export async function translatePost(postId: string, lang: string, permanent = false) {
     const url = `${postRoute(postId)}/translate`;
+    return doPost(url, {lang, permanent});
+}
+
+// translatePostFile translates a file attached to the post. The translated file is attached to a
+// reply in the thread once it is ready.
+export async function translatePostFile(postId: string, fileId: string, lang: string) {
+    const url = `${postRoute(postId)}/file/${fileId}/translate`;
     return doPost(url, {lang});
 }
 
+// getFileTranslations returns the translated files of a file attachment, keyed by language.
+export async function getFileTranslations(fileId: string) {
+    const url = `${baseRoute()}/file/${fileId}/translations`;
+    return doGet(url);
+}
+
+export async function getPostTranslations(postId: string) {
+    const url = `${postRoute(postId)}/translations`;
+    return doGet(url);
+}
+
+export async function getTranslationsBatch(postIds: string[], lang: string, generate = false) {
+    const url = `${baseRoute()}/translations/batch`;
+    return doPost(url, {post_ids: postIds, lang, generate});
+}
+
+// TranslatedAttachment is the translation of the text of a message attachment, in the order of
+// the attachments of the post.
+export type TranslatedAttachment = {
+    pretext?: string
+    title?: string
+    text?: string
+    fields?: Array<{title?: string, value?: string}>
+}
+
+// PostTranslation is the translation of the message of a post, along with the text of its message
+// attachments and the props translated for its type, keyed by path.
+export type PostTranslation = {
+    text: string
+    attachments?: TranslatedAttachment[]
+    props?: Record<string, string>
+}
+
+type BatchTranslations = {
+    translations?: Record<string, string>
+    attachments?: Record<string, TranslatedAttachment[]>
+    props?: Record<string, Record<string, string>>
+}
+
+type PendingBatch = {
+    postIds: Set<string>
+    promise: Promise<BatchTranslations>
+}
+
+const pendingBatches: Record<string, PendingBatch> = {};
+
+// maxBatchPosts is the most posts the server translates in a single batch request.
+const maxBatchPosts = 200;
+
+// getTranslationsBatches fetches the translations of the posts in as many batch requests as the
+// server needs, merging the responses.
+async function getTranslationsBatches(postIds: string[], lang: string): Promise<BatchTranslations> {
+    const requests = [];
+    for (let i = 0; i < postIds.length; i += maxBatchPosts) {
+        requests.push(getTranslationsBatch(postIds.slice(i, i + maxBatchPosts), lang));
+    }
+
+    const responses: BatchTranslations[] = await Promise.all(requests);
+    return responses.reduce((merged, response) => ({
+        translations: {...merged.translations, ...response.translations},
+        attachments: {...merged.attachments, ...response.attachments},
+        props: {...merged.props, ...response.props},
+    }), {translations: {}, attachments: {}, props: {}});
+}
+
+// loadPostTranslation fetches the translation of a post, grouping the posts requested while a
+// channel renders into a single batch request per language.
+export async function loadPostTranslation(postId: string, lang: string): Promise<PostTranslation | undefined> {
+    let batch = pendingBatches[lang];
+    if (!batch) {
+        const postIds = new Set<string>();
+        const promise = new Promise<BatchTranslations>((resolve, reject) => {
+            setTimeout(() => {
+                delete pendingBatches[lang];
+                getTranslationsBatches(Array.from(postIds), lang).then(resolve).catch(reject);
+            }, 50);
+        });
+        batch = {postIds, promise};
+        pendingBatches[lang] = batch;
+    }
+
+    batch.postIds.add(postId);
+    const response = await batch.promise;
+    const text = response.translations?.[postId];
+    if (text === undefined) {
+        return undefined;
+    }
+    return {
+        text,
+        attachments: response.attachments?.[postId],
+        props: response.props?.[postId],
+    };
+}
+
+export async function retryPostTranslations(postId: string) {
+    const url = `${postRoute(postId)}/translations/retry`;
+    return doPost(url, {});
+}
+
 export async function getTranslationLanguages() {
     const url = `${baseRoute()}/translation/languages`;
     return doGet(url);
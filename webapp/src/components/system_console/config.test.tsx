@@ -16,6 +16,21 @@ describe('Config', () => {
             translationLanguages: 'en,es,fr',
             translationBotName: 'TranslateBot',
             translateSystemMessages: false,
+            postTypeFilter: 'allowlist',
+            postTypes: 'custom_poll',
+            propPaths: 'custom_poll: question',
+            retranslateBotEdits: false,
+            retranslateWebhookEdits: false,
+            retranslatePluginEdits: false,
+            userTranslationsPerMinute: 5,
+            teamTranslationsPerMinute: 50,
+            userDailyTranslationQuota: 100,
+            teamDailyTranslationQuota: 1000,
+            channelMonthlyTokenBudget: 1000000,
+            teamMonthlyTokenBudget: 10000000,
+            pauseOverBudget: true,
+            costPerMillionTokens: 2.5,
+            maxFileTranslationSize: 512,
         },
         disabled: false,
         onChange: jest.fn(),
@@ -47,6 +62,21 @@ describe('Config', () => {
         expect(screen.getByText('Translation Languages')).toBeInTheDocument();
         expect(screen.getByText('Translation Bot')).toBeInTheDocument();
         expect(screen.getByText('Translate System Messages')).toBeInTheDocument();
+        expect(screen.getByText('Post Type Filter')).toBeInTheDocument();
+        expect(screen.getByText('Post Types')).toBeInTheDocument();
+        expect(screen.getByText('Custom Post Props')).toBeInTheDocument();
+        expect(screen.getByText('Re-translate Bot Edits')).toBeInTheDocument();
+        expect(screen.getByText('Re-translate Webhook Edits')).toBeInTheDocument();
+        expect(screen.getByText('Re-translate Plugin and Integration Edits')).toBeInTheDocument();
+        expect(screen.getByText('On-demand translations per user per minute')).toBeInTheDocument();
+        expect(screen.getByText('On-demand translations per team per minute')).toBeInTheDocument();
+        expect(screen.getByText('Daily on-demand translations per user')).toBeInTheDocument();
+        expect(screen.getByText('Daily on-demand translations per team')).toBeInTheDocument();
+        expect(screen.getByText('Monthly token budget per channel')).toBeInTheDocument();
+        expect(screen.getByText('Monthly token budget per team')).toBeInTheDocument();
+        expect(screen.getByText('Pause Translations Over Budget')).toBeInTheDocument();
+        expect(screen.getByText('Cost per million tokens')).toBeInTheDocument();
+        expect(screen.getByText('Maximum file size to translate (KB)')).toBeInTheDocument();
 
         // Check input values are set correctly
         expect(screen.getByDisplayValue('en,es,fr')).toBeInTheDocument();
@@ -137,6 +167,22 @@ describe('Config', () => {
         );
     });
 
+    test('calls onChange when Post Type Filter is changed', () => {
+        // Act
+        renderWithIntl(<Config {...defaultProps}/>);
+
+        fireEvent.change(screen.getByDisplayValue('Translate only the listed post types'), {target: {value: 'denylist'}});
+
+        // Assert
+        expect(defaultProps.onChange).toHaveBeenCalledWith(
+            'config-id',
+            {
+                ...defaultProps.value,
+                postTypeFilter: 'denylist',
+            },
+        );
+    });
+
     test('registers save action on mount and unregisters on unmount', () => {
         // Act
         const {unmount} = renderWithIntl(<Config {...defaultProps}/>);
@@ -6,13 +6,28 @@ import styled from 'styled-components';
 import {FormattedMessage, useIntl} from 'react-intl';
 
 import Panel from './panel';
-import {BooleanItem, ItemList, TextItem} from './item';
+import {BooleanItem, ItemList, SelectionItem, SelectionItemOption, TextItem} from './item';
 
 type Config = {
     enableTranslations: boolean
     translationLanguages: string
     translationBotName: string
     translateSystemMessages: boolean
+    postTypeFilter: string
+    postTypes: string
+    propPaths: string
+    retranslateBotEdits: boolean
+    retranslateWebhookEdits: boolean
+    retranslatePluginEdits: boolean
+    userTranslationsPerMinute: number
+    teamTranslationsPerMinute: number
+    userDailyTranslationQuota: number
+    teamDailyTranslationQuota: number
+    channelMonthlyTokenBudget: number
+    teamMonthlyTokenBudget: number
+    pauseOverBudget: boolean
+    costPerMillionTokens: number
+    maxFileTranslationSize: number
 }
 
 type Props = {
@@ -46,6 +61,21 @@ const defaultConfig = {
     translationLanguages: '',
     translationBotName: '',
     translateSystemMessages: false,
+    postTypeFilter: '',
+    postTypes: '',
+    propPaths: '',
+    retranslateBotEdits: false,
+    retranslateWebhookEdits: false,
+    retranslatePluginEdits: false,
+    userTranslationsPerMinute: 0,
+    teamTranslationsPerMinute: 0,
+    userDailyTranslationQuota: 0,
+    teamDailyTranslationQuota: 0,
+    channelMonthlyTokenBudget: 0,
+    teamMonthlyTokenBudget: 0,
+    pauseOverBudget: false,
+    costPerMillionTokens: 0,
+    maxFileTranslationSize: 256,
 };
 
 const BetaMessage = () => (
@@ -113,7 +143,109 @@ const Config = (props: Props) => {
                         label={intl.formatMessage({defaultMessage: 'Translate System Messages'})}
                         value={value.translateSystemMessages}
                         onChange={(to) => props.onChange(props.id, {...value, translateSystemMessages: to})}
-                        helpText={intl.formatMessage({defaultMessage: 'Enable translation of system messages. When disabled, only user messages will be translated.'})}
+                        helpText={intl.formatMessage({defaultMessage: 'Enable translation of the custom posts of integrations. System messages of the server are never translated, as they can\'t be updated.'})}
+                    />
+                    <SelectionItem
+                        label={intl.formatMessage({defaultMessage: 'Post Type Filter'})}
+                        value={value.postTypeFilter}
+                        onChange={(e) => props.onChange(props.id, {...value, postTypeFilter: e.target.value})}
+                    >
+                        <SelectionItemOption value=''>{intl.formatMessage({defaultMessage: 'Follow the system messages setting'})}</SelectionItemOption>
+                        <SelectionItemOption value='allowlist'>{intl.formatMessage({defaultMessage: 'Translate only the listed post types'})}</SelectionItemOption>
+                        <SelectionItemOption value='denylist'>{intl.formatMessage({defaultMessage: 'Translate every post type but the listed ones'})}</SelectionItemOption>
+                    </SelectionItem>
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Post Types'})}
+                        value={value.postTypes}
+                        onChange={(e) => props.onChange(props.id, {...value, postTypes: e.target.value})}
+                        helpText={intl.formatMessage({defaultMessage: 'Comma-separated list of post types for the post type filter (e.g. "custom_poll,custom_github"). Regular posts are always translated, and system messages never are.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Custom Post Props'})}
+                        multiline={true}
+                        value={value.propPaths}
+                        onChange={(e) => props.onChange(props.id, {...value, propPaths: e.target.value})}
+                        helpText={intl.formatMessage({defaultMessage: 'Props of custom post types to translate, one post type per line followed by its comma-separated paths (e.g. "custom_poll: question, options.*.text"). Use * to go through every item of a list.'})}
+                    />
+                    <BooleanItem
+                        label={intl.formatMessage({defaultMessage: 'Re-translate Bot Edits'})}
+                        value={value.retranslateBotEdits}
+                        onChange={(to) => props.onChange(props.id, {...value, retranslateBotEdits: to})}
+                        helpText={intl.formatMessage({defaultMessage: 'Translate messages again when a bot edits them outside of a user session.'})}
+                    />
+                    <BooleanItem
+                        label={intl.formatMessage({defaultMessage: 'Re-translate Webhook Edits'})}
+                        value={value.retranslateWebhookEdits}
+                        onChange={(to) => props.onChange(props.id, {...value, retranslateWebhookEdits: to})}
+                        helpText={intl.formatMessage({defaultMessage: 'Translate messages posted by webhooks again when they are edited.'})}
+                    />
+                    <BooleanItem
+                        label={intl.formatMessage({defaultMessage: 'Re-translate Plugin and Integration Edits'})}
+                        value={value.retranslatePluginEdits}
+                        onChange={(to) => props.onChange(props.id, {...value, retranslatePluginEdits: to})}
+                        helpText={intl.formatMessage({defaultMessage: 'Translate messages again when another plugin or integration edits them without a user session.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'On-demand translations per user per minute'})}
+                        type='number'
+                        value={String(value.userTranslationsPerMinute ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, userTranslationsPerMinute: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'How many posts a user can translate on demand each minute. Set to 0 for no limit.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'On-demand translations per team per minute'})}
+                        type='number'
+                        value={String(value.teamTranslationsPerMinute ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, teamTranslationsPerMinute: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'How many posts the members of a team can translate on demand each minute, all together. Set to 0 for no limit.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Daily on-demand translations per user'})}
+                        type='number'
+                        value={String(value.userDailyTranslationQuota ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, userDailyTranslationQuota: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'How many posts a user can translate on demand each day (UTC). Set to 0 for no quota.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Daily on-demand translations per team'})}
+                        type='number'
+                        value={String(value.teamDailyTranslationQuota ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, teamDailyTranslationQuota: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'How many posts the members of a team can translate on demand each day (UTC), all together. Set to 0 for no quota.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Monthly token budget per channel'})}
+                        type='number'
+                        value={String(value.channelMonthlyTokenBudget ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, channelMonthlyTokenBudget: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'Estimated tokens a channel can use for translations each month. Channel admins are warned at 80% and when it is exceeded. Set to 0 for no budget.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Monthly token budget per team'})}
+                        type='number'
+                        value={String(value.teamMonthlyTokenBudget ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, teamMonthlyTokenBudget: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'Estimated tokens the channels of a team can use for translations each month, all together. Set to 0 for no budget.'})}
+                    />
+                    <BooleanItem
+                        label={intl.formatMessage({defaultMessage: 'Pause Translations Over Budget'})}
+                        value={value.pauseOverBudget}
+                        onChange={(to) => props.onChange(props.id, {...value, pauseOverBudget: to})}
+                        helpText={intl.formatMessage({defaultMessage: 'Stop translating new messages automatically in channels whose channel or team went over its monthly budget, until the next month.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Cost per million tokens'})}
+                        type='number'
+                        value={String(value.costPerMillionTokens ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, costPerMillionTokens: parseFloat(e.target.value) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'Price of a million tokens of the translation backend, used to report the cost of the translations.'})}
+                    />
+                    <TextItem
+                        label={intl.formatMessage({defaultMessage: 'Maximum file size to translate (KB)'})}
+                        type='number'
+                        value={String(value.maxFileTranslationSize ?? '')}
+                        onChange={(e) => props.onChange(props.id, {...value, maxFileTranslationSize: parseInt(e.target.value, 10) || 0})}
+                        helpText={intl.formatMessage({defaultMessage: 'Largest VTT, SRT, text or Markdown attachment that can be translated on demand. Zero uses the default of 256 KB.'})}
                     />
                 </ItemList>
             </Panel>
@@ -2,7 +2,7 @@
 // See LICENSE.txt for license information.
 
 import React from 'react';
-import {render, screen} from '@testing-library/react';
+import {render, screen, waitFor} from '@testing-library/react';
 import {Provider} from 'react-redux';
 import configureStore from 'redux-mock-store';
 import {IntlProvider} from 'react-intl';
@@ -70,6 +70,134 @@ describe('TranslatedPost', () => {
         expect(screen.getByText('Translating')).toBeInTheDocument();
     });
 
+    test('renders original message when translation to the user language failed', () => {
+        // Arrange
+        const store = mockStore({
+            entities: {
+                users: {
+                    currentUserId: 'user1',
+                    profiles: {
+                        user1: {
+                            id: 'user1',
+                            locale: 'es',
+                        },
+                    },
+                },
+                preferences: {
+                    myPreferences: {},
+                },
+                channels: {
+                    channels: {},
+                },
+                teams: {
+                    teams: {},
+                },
+                general: {
+                    config: {},
+                },
+            },
+        });
+
+        const post = {
+            id: 'post1',
+            message: 'Original message',
+            type: 'custom_translation',
+            channel_id: 'channel1',
+            props: {
+                translations: {},
+                translation_status: {
+                    es: {status: 'failed', reason: 'timeout'},
+                    fr: {status: 'pending'},
+                },
+            },
+        };
+
+        // Act
+        render(
+            <IntlProvider locale='en'>
+                <Provider store={store}>
+                    <TranslatedPost post={post}/>
+                </Provider>
+            </IntlProvider>,
+        );
+
+        // Assert
+        expect(screen.queryByTestId('loadingSpinner')).not.toBeInTheDocument();
+        expect(window.PostUtils.formatText).toHaveBeenCalledWith(
+            'Original message',
+            expect.anything(),
+        );
+    });
+
+    test('fetches stored translations of finished languages', async () => {
+        // Arrange
+        global.fetch = jest.fn().mockResolvedValue({
+            ok: true,
+            json: jest.fn().mockResolvedValue({translations: {post1: 'Mensaje traducido'}, missing: []}),
+        });
+        const store = mockStore({
+            entities: {
+                users: {
+                    currentUserId: 'user1',
+                    profiles: {
+                        user1: {
+                            id: 'user1',
+                            locale: 'es',
+                        },
+                    },
+                },
+                preferences: {
+                    myPreferences: {},
+                },
+                channels: {
+                    channels: {},
+                },
+                teams: {
+                    teams: {},
+                },
+                general: {
+                    config: {},
+                },
+            },
+        });
+
+        const post = {
+            id: 'post1',
+            message: 'Original message',
+            type: 'custom_translation',
+            channel_id: 'channel1',
+            props: {
+                translation_status: {
+                    es: {status: 'done'},
+                },
+            },
+        };
+
+        // Act
+        render(
+            <IntlProvider locale='en'>
+                <Provider store={store}>
+                    <TranslatedPost post={post}/>
+                </Provider>
+            </IntlProvider>,
+        );
+
+        // Assert
+        await waitFor(() => {
+            expect(window.PostUtils.formatText).toHaveBeenCalledWith(
+                'Mensaje traducido',
+                expect.anything(),
+            );
+        });
+        expect(global.fetch).toHaveBeenCalledWith(
+            '/plugins/mattermost-channel-translations/translations/batch',
+            expect.objectContaining({
+                method: 'POST',
+                body: JSON.stringify({post_ids: ['post1'], lang: 'es', generate: false}),
+            }),
+        );
+    });
+
     test('renders translated text based on user preference', () => {
         // Arrange
         const store = mockStore({
@@ -1,7 +1,7 @@
 // Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
 // See LICENSE.txt for license information.
 
-import React from 'react';
+import React, {useEffect, useState} from 'react';
 import styled from 'styled-components';
 import {useSelector} from 'react-redux';
 
@@ -10,6 +10,8 @@ import {GlobalState} from '@mattermost/types/store';
 import {UserProfile} from '@mattermost/types/users';
 
 import LoadingSpinner from 'src/components/widgets/loading_spinner';
+import {getPostTranslationProgress} from 'src/selectors';
+import {loadPostTranslation} from 'src/client';
 
 import PostText from './post_text';
 
@@ -45,7 +47,31 @@ export const TranslatedPost = (props: Props) => {
         loading = true;
     }
 
-    const translations = post.props?.translations || {};
+    // Translations are kept in the plugin store and fetched once the post reports them as done
+    const targetLanguage = currentUserTranslationPreference || currentUserLocale;
+    const [stored, setStored] = useState<{message: string, lang: string, text: string} | null>(null);
+    const postStatuses: Record<string, {status: string}> = post.props?.translation_status || {};
+    const targetDone = postStatuses[targetLanguage]?.status === 'done';
+    useEffect(() => {
+        if (!targetDone) {
+            return;
+        }
+        const message = post.message;
+        loadPostTranslation(post.id, targetLanguage).then((translation) => {
+            if (translation?.text) {
+                setStored({message, lang: targetLanguage, text: translation.text});
+            }
+        }).catch(() => {
+            // Keep showing the original message
+        });
+    }, [post.id, post.message, targetLanguage, targetDone]);
+    const storedTranslations = stored && stored.message === post.message ? {[stored.lang]: stored.text} : {};
+
+    // Languages pushed over the websocket are shown until the post is updated with them
+    const progress = useSelector((state: GlobalState) => getPostTranslationProgress(state, post.id));
+    const currentProgress = progress && progress.message === post.message ? progress : null;
+    const translations = {...(post.props?.translations || {}), ...storedTranslations, ...(currentProgress?.translations || {})};
+    const statuses = {...postStatuses, ...(currentProgress?.statuses || {})};
 
     // First try user preference, then user locale
     let translationKey = '';
@@ -60,6 +86,11 @@ export const TranslatedPost = (props: Props) => {
         message = translations[translationKey];
     }
 
+    // Stop waiting once the wanted language is no longer pending, e.g. it failed or was skipped
+    if (loading && Object.keys(statuses).length > 0 && statuses[targetLanguage]?.status !== 'pending') {
+        loading = false;
+    }
+
     return (
         <PostContainer>
             {loading && (
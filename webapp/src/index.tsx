@@ -11,16 +11,25 @@ import {GlobalState} from '@mattermost/types/store';
 import manifest from '@/manifest';
 
 import Config from './components/system_console/config';
-import {getChannelTranslationStatus, toggleChannelTranslations, translatePost} from './client';
+import {getChannelTranslationStatus, getPostTranslations, retryPostTranslations, toggleChannelTranslations, translatePost, translatePostFile} from './client';
 import TranslationLanguageSetting from './components/user_settings/translation_language';
 import {setupRedux} from './redux';
 import {doOpenTranslationsModal, useOpenTranslationsModal} from './hooks';
 import {TranslatedPost} from './components/translated_post';
 import TranslationsModal from './components/translations_modal';
-import {getTranslationsModalPost} from './selectors';
+import {canManageChannelTranslations, getTranslationsModalPost, getUserTranslationLanguage} from './selectors';
+import {createMessageWillFormatHook, rerenderPost, showPostTranslation} from './message_format';
 
 type WebappStore = Store<GlobalState, Action<Record<string, unknown>>>
 
+// translatableFileExtensions are the extensions of the transcripts and text files the plugin translates.
+const translatableFileExtensions = ['vtt', 'srt', 'txt', 'md', 'markdown'];
+
+// getTranslatableFiles returns the files attached to the post that the plugin translates.
+const getTranslatableFiles = (post: any): any[] => {
+    return (post?.metadata?.files || []).filter((file: any) => translatableFileExtensions.includes(file.extension?.toLowerCase()));
+};
+
 export default class Plugin {
     // eslint-disable-next-line @typescript-eslint/no-unused-vars, @typescript-eslint/no-empty-function
     public async initialize(registry: any, store: WebappStore) {
@@ -57,30 +66,74 @@ export default class Plugin {
                 <i className='icon icon-globe'/>
                 <FormattedMessage defaultMessage='Translate again'/>
             </>,
-            (postId: any) => {
+            async (postId: any) => {
                 const state = store.getState();
                 const lang = (state.entities.preferences.myPreferences['pp_mattermost-channel-translatio--translation_language'] || {}).value || 'en';
-                translatePost(postId, lang);
+
+                // The translation is only for the current user, so it is shown without updating the post
+                const response = await translatePost(postId, lang);
+                store.dispatch({
+                    type: 'RECEIVED_TRANSLATION_PROGRESS',
+                    data: {
+                        post_id: postId,
+                        message: response.originalText,
+                        language: response.targetLanguage,
+                        status: 'done',
+                        translation: response.translatedText,
+                    },
+                } as any);
+                showPostTranslation(store, postId, response.originalText, response.targetLanguage, {
+                    text: response.translatedText,
+                    attachments: response.attachments,
+                    props: response.props,
+                });
             },
             (post: any) => {
                 return post.type !== 'custom_translation';
             },
         );
 
+        // Register the "Translate for everyone" button
+        registry.registerPostDropdownMenuAction(
+            <>
+                <i className='icon icon-globe'/>
+                <FormattedMessage defaultMessage='Translate for everyone'/>
+            </>,
+            (postId: any) => {
+                const lang = getUserTranslationLanguage(store.getState());
+                translatePost(postId, lang, true);
+            },
+            (post: any) => {
+                // Only users managing the channel can store translations on the post
+                return post.type !== 'custom_translation' && canManageChannelTranslations(store.getState(), post.channel_id);
+            },
+        );
+
         // Register the "View translations" button
         registry.registerPostDropdownMenuAction(
             <>
                 <i className='icon icon-globe'/>
                 <FormattedMessage defaultMessage='View translations'/>
             </>,
-            (postId: any) => {
+            async (postId: any) => {
             // Find the post in the store
                 const state = store.getState();
                 const postsById = state.entities.posts.posts;
                 const post = postsById[postId];
+                if (!post) {
+                    return;
+                }
 
-                if (post && post.props?.translations) {
-                    doOpenTranslationsModal(post, store.dispatch);
+                let translations = post.props?.translations || {};
+                try {
+                    const response = await getPostTranslations(postId);
+                    translations = {...translations, ...response.translations};
+                } catch (e) {
+                    // Show whatever translations the post still carries
+                }
+
+                if (Object.keys(translations).length > 0) {
+                    doOpenTranslationsModal({...post, props: {...post.props, translations}}, store.dispatch);
                 }
             },
             (post: any) => {
@@ -89,6 +142,39 @@ export default class Plugin {
             },
         );
 
+        // Register the "Retry failed translations" button
+        registry.registerPostDropdownMenuAction(
+            <>
+                <i className='icon icon-refresh'/>
+                <FormattedMessage defaultMessage='Retry failed translations'/>
+            </>,
+            (postId: any) => {
+                retryPostTranslations(postId);
+            },
+            (post: any) => {
+                // Only show for posts with at least one failed language
+                return Object.values(post.props?.translation_status || {}).some((status: any) => status.status === 'failed');
+            },
+        );
+
+        // Register the "Translate files" button
+        registry.registerPostDropdownMenuAction(
+            <>
+                <i className='icon icon-file-text-outline'/>
+                <FormattedMessage defaultMessage='Translate files'/>
+            </>,
+            (postId: any) => {
+                const post = store.getState().entities.posts.posts[postId];
+                const lang = getUserTranslationLanguage(store.getState());
+                for (const file of getTranslatableFiles(post)) {
+                    translatePostFile(postId, file.id, lang);
+                }
+            },
+            (post: any) => {
+                return getTranslatableFiles(post).length > 0;
+            },
+        );
+
         // Render the translations modal outside the component tree
         // We use ReactDOM.createPortal to mount it at the root level
         registry.registerRootComponent(() => {
@@ -106,7 +192,13 @@ export default class Plugin {
             );
         });
 
+        // Posts translated by earlier versions of the plugin had their type replaced
         registry.registerPostTypeComponent('custom_translation', TranslatedPost);
+        registry.registerMessageWillFormatHook(createMessageWillFormatHook(store));
+        registry.registerWebSocketEventHandler(`custom_${manifest.id}_translation_progress`, (msg: any) => {
+            store.dispatch({type: 'RECEIVED_TRANSLATION_PROGRESS', data: msg.data} as any);
+            rerenderPost(store, msg.data.post_id);
+        });
         registry.registerChannelHeaderMenuAction(
             <TranslationButton/>,
             async (channelId: string) => {
@@ -140,6 +232,17 @@ export default class Plugin {
                             helpText: 'Select your preferred language for channel translations. This setting applies to all channels where translations are enabled.',
                             component: TranslationLanguageSetting,
                         },
+                        {
+                            type: 'radio',
+                            name: 'outbound_translation',
+                            title: 'Outbound Translation',
+                            helpText: 'Translate your messages into the primary language of the channel before they are posted. The original message is kept with the post.',
+                            default: 'off',
+                            options: [
+                                {value: 'on', text: 'On'},
+                                {value: 'off', text: 'Off'},
+                            ],
+                        },
                     ],
                 },
             ],
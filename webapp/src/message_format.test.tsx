@@ -0,0 +1,222 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {applyPostTranslation, createMessageWillFormatHook, showPostTranslation} from './message_format';
+
+// Mock fetch
+global.fetch = jest.fn();
+
+describe('createMessageWillFormatHook', () => {
+    const makeStore = (plugin: any = {}) => {
+        const state = {
+            entities: {
+                users: {
+                    currentUserId: 'user1',
+                    profiles: {
+                        user1: {id: 'user1', locale: 'es'},
+                    },
+                },
+                preferences: {
+                    myPreferences: {},
+                },
+                posts: {
+                    posts: {},
+                },
+            },
+            'plugins-mattermost-channel-translations': plugin,
+        };
+        return {
+            getState: () => state,
+            dispatch: jest.fn(),
+        } as any;
+    };
+
+    beforeEach(() => {
+        jest.clearAllMocks();
+    });
+
+    test('keeps the message of posts without translation state', () => {
+        // Arrange
+        const hook = createMessageWillFormatHook(makeStore());
+        const post = {id: 'post1', message: 'Hello', type: 'system_header_change', props: {}};
+
+        // Act
+        const result = hook(post, 'Hello');
+
+        // Assert
+        expect(result).toBe('Hello');
+        expect(global.fetch).not.toHaveBeenCalled();
+    });
+
+    test('uses translations received over the websocket', () => {
+        // Arrange
+        const hook = createMessageWillFormatHook(makeStore({
+            translationProgress: {
+                post1: {message: 'Hello', translations: {es: 'Hola'}, statuses: {es: {status: 'done'}}},
+            },
+        }));
+        const post = {id: 'post1', message: 'Hello', type: '', props: {translation_status: {es: {status: 'pending'}}}};
+
+        // Act
+        const result = hook(post, 'Hello');
+
+        // Assert
+        expect(result).toBe('Hola');
+    });
+
+    test('uses translations made for the current user on posts without translation state', () => {
+        // Arrange
+        const hook = createMessageWillFormatHook(makeStore({
+            translationProgress: {
+                post1: {message: 'Hello', translations: {es: 'Hola'}, statuses: {es: {status: 'done'}}},
+            },
+        }));
+        const post = {id: 'post1', message: 'Hello', type: '', props: {}};
+
+        // Act
+        const result = hook(post, 'Hello');
+
+        // Assert
+        expect(result).toBe('Hola');
+    });
+
+    test('loads finished translations and renders the post again', async () => {
+        // Arrange
+        (global.fetch as jest.Mock).mockResolvedValue({
+            ok: true,
+            json: jest.fn().mockResolvedValue({translations: {post2: 'Adiós'}, missing: []}),
+        });
+        const store = makeStore();
+        store.getState().entities.posts.posts.post2 = {id: 'post2', message: 'Goodbye'};
+        const hook = createMessageWillFormatHook(store);
+        const post = {id: 'post2', message: 'Goodbye', type: '', update_at: 1, props: {translation_status: {es: {status: 'done'}}}};
+
+        // Act
+        const first = hook(post, 'Goodbye');
+        await new Promise((resolve) => setTimeout(resolve, 100));
+        const second = hook(post, 'Goodbye');
+
+        // Assert
+        expect(first).toBe('Goodbye');
+        expect(second).toBe('Adiós');
+        expect(store.dispatch).toHaveBeenCalledWith(expect.objectContaining({type: 'RECEIVED_POST'}));
+    });
+
+    test('loads translations the current user asked for on posts without translation state', async () => {
+        // Arrange
+        (global.fetch as jest.Mock).mockResolvedValue({
+            ok: true,
+            json: jest.fn().mockResolvedValue({translations: {post5: 'Buenos días'}, missing: []}),
+        });
+        const store = makeStore();
+        store.getState().entities.posts.posts.post5 = {id: 'post5', message: 'Good morning'};
+        const hook = createMessageWillFormatHook(store);
+        const post = {id: 'post5', message: 'Good morning', type: '', update_at: 1, props: {}};
+
+        // Act
+        hook(post, 'Good morning');
+        await new Promise((resolve) => setTimeout(resolve, 100));
+        const result = hook(post, 'Good morning');
+
+        // Assert
+        expect(result).toBe('Buenos días');
+    });
+
+    test('waits for pending translations to arrive over the websocket', async () => {
+        // Arrange
+        const hook = createMessageWillFormatHook(makeStore());
+        const post = {id: 'post6', message: 'Hello', type: '', update_at: 1, props: {translation_status: {es: {status: 'pending'}}}};
+
+        // Act
+        const result = hook(post, 'Hello');
+        await new Promise((resolve) => setTimeout(resolve, 100));
+
+        // Assert
+        expect(result).toBe('Hello');
+        expect(global.fetch).not.toHaveBeenCalled();
+    });
+
+    test('swaps the translated attachments in the post', async () => {
+        // Arrange
+        (global.fetch as jest.Mock).mockResolvedValue({
+            ok: true,
+            json: jest.fn().mockResolvedValue({translations: {post3: ''}, missing: [], attachments: {post3: [{text: 'Despliegue terminado'}]}}),
+        });
+        const store = makeStore();
+        const post = {id: 'post3', message: '', type: 'slack_attachment', update_at: 1, props: {attachments: [{text: 'Deploy finished'}], translation_status: {es: {status: 'done'}}}};
+        store.getState().entities.posts.posts.post3 = post;
+        const hook = createMessageWillFormatHook(store);
+
+        // Act
+        hook(post, '');
+        await new Promise((resolve) => setTimeout(resolve, 100));
+
+        // Assert
+        expect(store.dispatch).toHaveBeenCalledWith({
+            type: 'RECEIVED_POST',
+            data: expect.objectContaining({
+                props: expect.objectContaining({attachments: [{text: 'Despliegue terminado'}]}),
+                translation_lang: 'es',
+            }),
+        });
+    });
+
+    test('shows translations made for the current user along with their attachments', () => {
+        // Arrange
+        const store = makeStore();
+        const post = {id: 'post4', message: 'Deploy', type: '', props: {attachments: [{text: 'Finished'}]}};
+        store.getState().entities.posts.posts.post4 = post;
+        const hook = createMessageWillFormatHook(store);
+
+        // Act
+        showPostTranslation(store, 'post4', 'Deploy', 'es', {text: 'Despliegue', attachments: [{text: 'Terminado'}]});
+        const result = hook(post, 'Deploy');
+
+        // Assert
+        expect(result).toBe('Despliegue');
+        expect(store.dispatch).toHaveBeenCalledWith({
+            type: 'RECEIVED_POST',
+            data: expect.objectContaining({props: {attachments: [{text: 'Terminado'}]}}),
+        });
+    });
+});
+
+describe('applyPostTranslation', () => {
+    const post = {
+        id: 'post1',
+        message: 'Hello',
+        props: {
+            attachments: [{pretext: 'Note', title: 'Alert', text: '', color: '#f00', fields: [{title: 'Owner', value: 'Ann', short: true}]}],
+            card: {options: [{text: 'Yes'}, {text: 'No'}]},
+        },
+    };
+
+    test('replaces the text of attachments and props and keeps everything else', () => {
+        // Act
+        const translated = applyPostTranslation(post, 'es', {
+            text: 'Hola',
+            attachments: [{pretext: 'Nota', title: 'Alerta', fields: [{title: 'Responsable'}]}],
+            props: {'card.options.1.text': 'No, gracias'},
+        });
+
+        // Assert
+        expect(translated.props).toEqual({
+            attachments: [{pretext: 'Nota', title: 'Alerta', text: '', color: '#f00', fields: [{title: 'Responsable', value: 'Ann', short: true}]}],
+            card: {options: [{text: 'Yes'}, {text: 'No, gracias'}]},
+        });
+        expect(translated.translation_original_props).toBe(post.props);
+        expect(post.props.attachments[0].title).toBe('Alert');
+    });
+
+    test('translates from the original props when the post is already translated', () => {
+        // Arrange
+        const spanish = applyPostTranslation(post, 'es', {text: 'Hola', attachments: [{title: 'Alerta'}]});
+
+        // Act
+        const german = applyPostTranslation(spanish, 'de', {text: 'Hallo'});
+
+        // Assert
+        expect(german.props).toEqual(post.props);
+        expect(german.translation_lang).toBe('de');
+    });
+});
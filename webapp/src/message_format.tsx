@@ -0,0 +1,157 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {Store, Action} from 'redux';
+
+import {GlobalState} from '@mattermost/types/store';
+
+import {PostTranslation, loadPostTranslation} from './client';
+import {getPostTranslationProgress, getUserTranslationLanguage} from './selectors';
+
+type LoadedTranslation = {
+    message: string
+    lang: string
+    translation: PostTranslation
+}
+
+const loadedTranslations: Record<string, LoadedTranslation> = {};
+const requestedTranslations = new Set<string>();
+
+// rerenderPost makes the webapp render the post again, so its message is formatted with the
+// translation that just arrived.
+export const rerenderPost = (store: Store<GlobalState, Action<Record<string, unknown>>>, postId: string) => {
+    const post = store.getState().entities.posts.posts[postId];
+    if (post) {
+        store.dispatch({type: 'RECEIVED_POST', data: {...post}} as any);
+    }
+};
+
+// hasTranslatedContent checks if the translation covers the message attachments or props of the
+// post, which are rendered from the post itself rather than through the message.
+const hasTranslatedContent = (translation: PostTranslation): boolean => {
+    return Boolean(translation.attachments?.length) || Object.keys(translation.props || {}).length > 0;
+};
+
+// setPropText replaces the text found in the props at the path, where every element the path
+// goes through is named by its key or position.
+const setPropText = (value: any, path: string[], text: string) => {
+    if (!value || typeof value !== 'object') {
+        return;
+    }
+
+    const [key, ...rest] = path;
+    if (rest.length === 0) {
+        if (typeof value[key] === 'string') {
+            value[key] = text;
+        }
+        return;
+    }
+    setPropText(value[key], rest, text);
+};
+
+// applyPostTranslation returns a copy of the post with the text of its message attachments and
+// translated props replaced by their translation into the language. The props as they were posted
+// are kept along, so the post can be translated into another language later on.
+export const applyPostTranslation = (post: any, lang: string, translation: PostTranslation): any => {
+    const originalProps = post.translation_original_props || post.props || {};
+    const props = JSON.parse(JSON.stringify(originalProps));
+
+    (translation.attachments || []).forEach((translated, i) => {
+        const attachment = props.attachments?.[i];
+        if (!attachment) {
+            return;
+        }
+        attachment.pretext = translated.pretext || attachment.pretext;
+        attachment.title = translated.title || attachment.title;
+        attachment.text = translated.text || attachment.text;
+        (translated.fields || []).forEach((translatedField, j) => {
+            const field = attachment.fields?.[j];
+            if (field) {
+                field.title = translatedField.title || field.title;
+                field.value = translatedField.value || field.value;
+            }
+        });
+    });
+
+    Object.entries(translation.props || {}).forEach(([path, text]) => {
+        setPropText(props, path.split('.'), text);
+    });
+
+    return {...post, props, translation_original_props: originalProps, translation_lang: lang};
+};
+
+// renderPostTranslation renders the post again with its translation. Message attachments and
+// props are not formatted like the message, so they are swapped in the post kept by the webapp,
+// and given back when the translation has none.
+const renderPostTranslation = (store: Store<GlobalState, Action<Record<string, unknown>>>, postId: string, lang: string, translation: PostTranslation) => {
+    const post: any = store.getState().entities.posts.posts[postId];
+    if (post && (hasTranslatedContent(translation) || post.translation_original_props)) {
+        store.dispatch({type: 'RECEIVED_POST', data: applyPostTranslation(post, lang, translation)} as any);
+        return;
+    }
+    rerenderPost(store, postId);
+};
+
+// showPostTranslation shows a translation of the post that was just made for the current user.
+export const showPostTranslation = (store: Store<GlobalState, Action<Record<string, unknown>>>, postId: string, message: string, lang: string, translation: PostTranslation) => {
+    loadedTranslations[postId] = {message, lang, translation};
+    renderPostTranslation(store, postId, lang, translation);
+};
+
+// createMessageWillFormatHook returns the hook that swaps the message of translated posts with
+// their translation into the current user's language. The translation state is kept in the post
+// props, so posts of every type are translated without replacing how they are rendered.
+export const createMessageWillFormatHook = (store: Store<GlobalState, Action<Record<string, unknown>>>) => {
+    return (post: any, message: string): string => {
+        if (!post || post.type === 'custom_translation') {
+            return message;
+        }
+
+        const state = store.getState();
+        const lang = getUserTranslationLanguage(state);
+
+        const loaded = loadedTranslations[post.id];
+        const translation = loaded && loaded.message === post.message && loaded.lang === lang ? loaded.translation : null;
+
+        // The webapp replaces the post when it receives it again, e.g. after a reaction, which
+        // brings back the original attachments and props
+        if (translation && hasTranslatedContent(translation) && post.translation_lang !== lang) {
+            setTimeout(() => renderPostTranslation(store, post.id, lang, translation));
+        }
+
+        // Languages pushed over the websocket, or translated for the current user only, are shown
+        // until the post is updated with them
+        const progress = getPostTranslationProgress(state, post.id);
+        if (progress && progress.message === post.message && progress.translations[lang]) {
+            return progress.translations[lang];
+        }
+
+        if (translation) {
+            return translation.text || message;
+        }
+
+        // System messages are never translated, and pending languages arrive over the websocket.
+        // Other posts may have a stored translation, or one the current user asked for themselves,
+        // which don't show in the statuses.
+        const statuses = post.props?.translation_status;
+        if (post.type?.startsWith('system_') || statuses?.[lang]?.status === 'pending') {
+            return message;
+        }
+
+        const requestKey = `${post.id}:${lang}:${post.update_at}`;
+        if (!requestedTranslations.has(requestKey)) {
+            requestedTranslations.add(requestKey);
+            const postMessage = post.message;
+            loadPostTranslation(post.id, lang).then((loadedTranslation) => {
+                if (loadedTranslation && (loadedTranslation.text || hasTranslatedContent(loadedTranslation))) {
+                    loadedTranslations[post.id] = {message: postMessage, lang, translation: loadedTranslation};
+                    renderPostTranslation(store, post.id, lang, loadedTranslation);
+                }
+            }).catch(() => {
+                // Keep showing the original message
+            });
+        }
+
+        return message;
+    };
+};
@@ -1,7 +1,7 @@
 // Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
 // See LICENSE.txt for license information.
 
-import {setupRedux, translationsModal} from './redux';
+import {setupRedux, translationProgress, translationsModal} from './redux';
 
 describe('redux', () => {
     describe('translationsModal reducer', () => {
@@ -45,4 +45,39 @@ describe('redux', () => {
             expect(mockRegistry.registerReducer).toHaveBeenCalled();
         });
     });
+
+    describe('translationProgress reducer', () => {
+        test('should store received translations per post', () => {
+            // Arrange
+            const action = {
+                type: 'RECEIVED_TRANSLATION_PROGRESS',
+                data: {post_id: 'post1', message: 'Hello', language: 'es', status: 'done', translation: 'Hola'},
+            };
+
+            // Act
+            const result = translationProgress({}, action);
+
+            // Assert
+            expect(result.post1.translations).toEqual({es: 'Hola'});
+            expect(result.post1.statuses.es.status).toBe('done');
+        });
+
+        test('should drop progress of a previous message version', () => {
+            // Arrange
+            const state = {
+                post1: {message: 'Hello', translations: {es: 'Hola'}, statuses: {es: {status: 'done'}}},
+            };
+            const action = {
+                type: 'RECEIVED_TRANSLATION_PROGRESS',
+                data: {post_id: 'post1', message: 'Goodbye', language: 'fr', status: 'done', translation: 'Au revoir'},
+            };
+
+            // Act
+            const result = translationProgress(state, action);
+
+            // Assert
+            expect(result.post1.translations).toEqual({fr: 'Au revoir'});
+            expect(result.post1.statuses.es).toBeUndefined();
+        });
+    });
 });
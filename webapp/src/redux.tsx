@@ -12,9 +12,56 @@ export function translationsModal(state = false, action: {type: string, post: an
     }
 }
 
+export type TranslationProgress = {
+    message: string
+    translations: Record<string, string>
+    statuses: Record<string, {status: string, reason?: string}>
+}
+
+export type TranslationProgressAction = {
+    type: string
+    data: {
+        post_id: string
+        message: string
+        language: string
+        status: string
+        reason?: string
+        translation?: string
+    }
+}
+
+export function translationProgress(state: Record<string, TranslationProgress> = {}, action: TranslationProgressAction) {
+    switch (action.type) {
+    case 'RECEIVED_TRANSLATION_PROGRESS': {
+        const {post_id: postId, message, language, status, reason, translation} = action.data;
+
+        // Progress for a previous version of the message is dropped
+        const previous = state[postId]?.message === message ? state[postId] : {message, translations: {}, statuses: {}};
+        const translations = {...previous.translations};
+        if (translation) {
+            translations[language] = translation;
+        } else {
+            delete translations[language];
+        }
+
+        return {
+            ...state,
+            [postId]: {
+                message,
+                translations,
+                statuses: {...previous.statuses, [language]: {status, reason}},
+            },
+        };
+    }
+    default:
+        return state;
+    }
+}
+
 export async function setupRedux(registry: any) {
     const reducer = combineReducers({
         translationsModal,
+        translationProgress,
     });
     registry.registerReducer(reducer);
 }
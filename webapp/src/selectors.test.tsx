@@ -3,7 +3,13 @@
 
 import {GlobalState} from '@mattermost/types/store';
 
-import {getTranslationsModalPost} from './selectors';
+import {haveIChannelPermission} from 'mattermost-redux/selectors/entities/roles';
+
+import {canManageChannelTranslations, getPostTranslationProgress, getTranslationsModalPost, getUserTranslationLanguage} from './selectors';
+
+jest.mock('mattermost-redux/selectors/entities/roles', () => ({
+    haveIChannelPermission: jest.fn(),
+}));
 
 describe('selectors', () => {
     describe('getTranslationsModalPost', () => {
@@ -34,4 +40,98 @@ describe('selectors', () => {
             expect(result).toBeFalsy();
         });
     });
+
+    describe('getPostTranslationProgress', () => {
+        test('should return the progress of the given post', () => {
+            // Arrange
+            const progress = {message: 'Hello', translations: {es: 'Hola'}, statuses: {es: {status: 'done'}}};
+            const state = {
+                'plugins-mattermost-channel-translations': {
+                    translationProgress: {post1: progress},
+                },
+            } as unknown as GlobalState;
+
+            // Act
+            const result = getPostTranslationProgress(state, 'post1');
+
+            // Assert
+            expect(result).toBe(progress);
+        });
+
+        test('should return undefined if there is no progress for the post', () => {
+            // Arrange
+            const state = {} as unknown as GlobalState;
+
+            // Act
+            const result = getPostTranslationProgress(state, 'post1');
+
+            // Assert
+            expect(result).toBeUndefined();
+        });
+    });
+
+    describe('getUserTranslationLanguage', () => {
+        const makeState = (preference?: string) => ({
+            entities: {
+                users: {
+                    currentUserId: 'user1',
+                    profiles: {
+                        user1: {id: 'user1', locale: 'fr'},
+                    },
+                },
+                preferences: {
+                    myPreferences: preference ? {'pp_mattermost-channel-translatio--translation_language': {value: preference}} : {},
+                },
+            },
+        } as unknown as GlobalState);
+
+        test('should prefer the translation preference', () => {
+            expect(getUserTranslationLanguage(makeState('es'))).toBe('es');
+        });
+
+        test('should fall back to the user locale', () => {
+            expect(getUserTranslationLanguage(makeState())).toBe('fr');
+        });
+    });
+
+    describe('canManageChannelTranslations', () => {
+        const state = {
+            entities: {
+                channels: {
+                    channels: {
+                        open: {id: 'open', type: 'O', team_id: 'team1'},
+                        private: {id: 'private', type: 'P', team_id: 'team1'},
+                    },
+                },
+            },
+        } as unknown as GlobalState;
+
+        test('should check the permission to manage public channels', () => {
+            // Arrange
+            (haveIChannelPermission as jest.Mock).mockReturnValue(true);
+
+            // Act
+            const result = canManageChannelTranslations(state, 'open');
+
+            // Assert
+            expect(result).toBe(true);
+            expect(haveIChannelPermission).toHaveBeenCalledWith(state, 'team1', 'open', 'manage_public_channel_properties');
+        });
+
+        test('should check the permission to manage private channels', () => {
+            // Arrange
+            (haveIChannelPermission as jest.Mock).mockReturnValue(false);
+
+            // Act
+            const result = canManageChannelTranslations(state, 'private');
+
+            // Assert
+            expect(result).toBe(false);
+            expect(haveIChannelPermission).toHaveBeenCalledWith(state, 'team1', 'private', 'manage_private_channel_properties');
+        });
+
+        test('should return false for unknown channels', () => {
+            expect(canManageChannelTranslations(state, 'unknown')).toBe(false);
+        });
+    });
 });
@@ -3,6 +3,9 @@
 
 import {GlobalState} from '@mattermost/types/store';
 
+import {Permissions} from 'mattermost-redux/constants';
+import {haveIChannelPermission} from 'mattermost-redux/selectors/entities/roles';
+
 import manifest from './manifest';
 
 const pluginState = (state: GlobalState): any => {
@@ -14,3 +17,32 @@ export const getTranslationsModalPost = (state: GlobalState): any => {
     const plugin = pluginState(state);
     return plugin.translationsModal;
 };
+
+export const getPostTranslationProgress = (state: GlobalState, postId: string): any => {
+    const plugin = pluginState(state);
+    return plugin.translationProgress?.[postId];
+};
+
+// getUserTranslationLanguage returns the language the current user reads translations in: their
+// translation preference, or their locale when they have none.
+export const getUserTranslationLanguage = (state: GlobalState): string => {
+    const preference = state.entities.preferences.myPreferences['pp_mattermost-channel-translatio--translation_language'];
+    if (preference?.value) {
+        return preference.value;
+    }
+
+    const currentUser = state.entities.users.profiles[state.entities.users.currentUserId];
+    return currentUser?.locale || 'en';
+};
+
+// canManageChannelTranslations checks if the current user may store translations of the channel
+// posts for everyone, which requires managing the channel properties.
+export const canManageChannelTranslations = (state: GlobalState, channelId: string): boolean => {
+    const channel = state.entities.channels.channels[channelId];
+    if (!channel) {
+        return false;
+    }
+
+    const permission = channel.type === 'P' ? Permissions.MANAGE_PRIVATE_CHANNEL_PROPERTIES : Permissions.MANAGE_PUBLIC_CHANNEL_PROPERTIES;
+    return haveIChannelPermission(state, channel.team_id, channelId, permission);
+};
@@ -3,6 +3,7 @@
 
 export interface PluginRegistry {
     registerPostTypeComponent(typeName: string, component: React.ElementType)
+    registerMessageWillFormatHook(hook: (post: any, message: string) => string)
 
     // Add more if needed from https://developers.mattermost.com/extend/plugins/webapp/reference
 }